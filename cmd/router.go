@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"pr-reviewer-service/internal/handlers"
+	"pr-reviewer-service/internal/logging"
+	"pr-reviewer-service/internal/metrics"
+	"pr-reviewer-service/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// api holds the HTTP handlers served by the router.
+type api struct {
+	team         *handlers.TeamHandler
+	user         *handlers.UserHandler
+	pr           *handlers.PRHandler
+	identity     *handlers.IdentityHandler
+	webhook      *handlers.WebhookHandler
+	subscription *handlers.SubscriptionHandler
+	health       *handlers.HealthHandler
+	docs         *handlers.DocsHandler
+	// logger is the base of the per-request loggers.
+	logger *slog.Logger
+	// metrics, when set, records every request and serves /metrics.
+	metrics *metrics.Metrics
+
+	// timeout bounds the handling of a single request.
+	timeout time.Duration
+	// swagger enables the Swagger UI page at /swagger.
+	swagger bool
+}
+
+// newRouter registers every route of the service. Routes are described in
+// internal/openapi/openapi.json; router_test.go fails when the two differ.
+func newRouter(a *api) chi.Router {
+	r := chi.NewRouter()
+	if a.metrics != nil {
+		r.Use(a.metrics.Middleware)
+	}
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(a.logger))
+	r.Use(middleware.Timeout(a.timeout))
+
+	r.Post("/team/add", a.team.CreateTeam)
+	r.Get("/team/get", a.team.GetTeam)
+	r.Get("/team/settings", a.team.GetSettings)
+	r.Post("/team/settings", a.team.UpdateSettings)
+	r.Post("/team/deactivateUsers", a.team.DeactivateUsers)
+	r.Post("/team/addMembers", a.team.AddMembers)
+	r.Post("/team/removeMembers", a.team.RemoveMembers)
+	r.Post("/team/rename", a.team.RenameTeam)
+	r.Post("/team/archive", a.team.ArchiveTeam)
+	r.Post("/team/delete", a.team.DeleteTeam)
+	r.Put("/team/sync", a.team.SyncTeam)
+
+	r.Post("/users/setIsActive", a.user.SetIsActive)
+	r.Get("/users/getReview", a.user.GetReview)
+
+	r.Post("/pullRequest/create", a.pr.CreatePR)
+	r.Post("/pullRequest/merge", a.pr.MergePR)
+	r.Post("/pullRequest/reassign", a.pr.ReassignReviewer)
+	r.Post("/pullRequest/review", a.pr.SubmitReview)
+	r.Post("/pullRequest/close", a.pr.ClosePR)
+	r.Post("/pullRequest/reopen", a.pr.ReopenPR)
+	r.Post("/pullRequest/markReady", a.pr.MarkReady)
+
+	r.Post("/identities/set", a.identity.SetMapping)
+
+	r.Post("/webhooks/github", a.webhook.GitHub)
+	r.Post("/webhooks/gitlab", a.webhook.GitLab)
+
+	r.Post("/webhooks/subscriptions", a.subscription.Create)
+	r.Get("/webhooks/subscriptions", a.subscription.List)
+	r.Get("/webhooks/subscriptions/{id}", a.subscription.Get)
+	r.Put("/webhooks/subscriptions/{id}", a.subscription.Update)
+	r.Delete("/webhooks/subscriptions/{id}", a.subscription.Delete)
+
+	r.Get("/health", a.health.Health)
+	r.Get("/health/live", a.health.Live)
+	r.Get("/health/ready", a.health.Ready)
+
+	r.Get("/openapi.json", a.docs.Spec)
+	if a.swagger {
+		r.Get("/swagger", a.docs.SwaggerUI)
+	}
+	if a.metrics != nil {
+		r.Method("GET", "/metrics", a.metrics.Handler())
+	}
+
+	return r
+}
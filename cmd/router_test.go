@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/handlers"
+	"pr-reviewer-service/internal/metrics"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/openapi"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testAdminToken    = "admin-secret"
+	testGitHubSecret  = "github-secret"
+	testGitLabToken   = "gitlab-token"
+	specResourceName  = "openapi.json"
+	jsonContentPtrSeg = "/content/application~1json"
+)
+
+// discardSink stands in for the webhook dispatcher so the outbox relay runs
+// without delivering anything.
+type discardSink struct{}
+
+func (discardSink) Dispatch(context.Context, *models.Event) error { return nil }
+
+func (discardSink) RetryDue(context.Context, int, time.Duration) (int, error) { return 0, nil }
+
+func newTestRouter(t *testing.T) chi.Router {
+	t.Helper()
+	return newRouter(newTestAPI(t))
+}
+
+func newTestAPI(t *testing.T) *api {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	repos := store.Repos()
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterOpenReviews(repos.PR)
+	prService := service.NewPRService(store, repos.PR, repos.User, repos.Team, models.StrategyLeastLoaded, appMetrics)
+	identityService := service.NewIdentityService(store, repos.Identity, repos.User)
+
+	relay := service.NewOutboxRelay(repos.Outbox, discardSink{})
+	go relay.Run(t.Context())
+	deadline := time.Now().Add(time.Second)
+	for _, err := relay.Health(t.Context()); err != nil; _, err = relay.Health(t.Context()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox relay did not start: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return &api{
+		team:         handlers.NewTeamHandler(service.NewTeamService(store, repos.Team, repos.User), prService),
+		user:         handlers.NewUserHandler(service.NewUserService(store, repos.User, repos.PR)),
+		pr:           handlers.NewPRHandler(prService, testAdminToken),
+		identity:     handlers.NewIdentityHandler(identityService),
+		webhook:      handlers.NewWebhookHandler(service.NewWebhookService(prService, identityService), testGitHubSecret, testGitLabToken),
+		subscription: handlers.NewSubscriptionHandler(service.NewSubscriptionService(store, repos.Subscription)),
+		health:       handlers.NewHealthHandler(outboxRelayCheck(relay)),
+		docs:         handlers.NewDocsHandler(),
+		logger:       slog.New(slog.DiscardHandler),
+		timeout:      30 * time.Second,
+		swagger:      true,
+		metrics:      appMetrics,
+	}
+}
+
+// specTester replays requests through the router and checks both the request
+// and the response against the operation they hit in the OpenAPI document.
+type specTester struct {
+	t        *testing.T
+	router   http.Handler
+	doc      map[string]any
+	compiler *jsonschema.Compiler
+	replayed map[string]bool
+}
+
+func newSpecTester(t *testing.T, router http.Handler) *specTester {
+	t.Helper()
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(openapi.Spec))
+	if err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	if err := compiler.AddResource(specResourceName, doc); err != nil {
+		t.Fatalf("add spec: %v", err)
+	}
+
+	return &specTester{
+		t:        t,
+		router:   router,
+		doc:      doc.(map[string]any),
+		compiler: compiler,
+		replayed: make(map[string]bool),
+	}
+}
+
+// replayRequest describes one request. Zero fields are filled from the spec:
+// the body from the request body example and query parameters from the
+// parameter examples.
+type replayRequest struct {
+	pathParams map[string]string
+	query      url.Values
+	body       any
+	header     http.Header
+}
+
+// replay sends a request to the operation identified by method and spec path,
+// asserts the status and validates the response body. It returns the decoded
+// response body.
+func (s *specTester) replay(method string, path string, req replayRequest, wantStatus int) map[string]any {
+	s.t.Helper()
+	opPtr := "/paths/" + escapePointer(path) + "/" + strings.ToLower(method)
+	op, ok := s.lookup(opPtr).(map[string]any)
+	if !ok {
+		s.t.Fatalf("%s %s is not in the spec", method, path)
+	}
+	s.replayed[method+" "+path] = true
+
+	target := path
+	for name, value := range req.pathParams {
+		target = strings.ReplaceAll(target, "{"+name+"}", url.PathEscape(value))
+	}
+	query := req.query
+	if query == nil {
+		query = s.exampleQuery(opPtr, op)
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body []byte
+	if _, ok := op["requestBody"]; ok {
+		bodyPtr := s.resolve(opPtr+"/requestBody") + jsonContentPtrSeg
+		value := req.body
+		if value == nil {
+			value = s.lookup(bodyPtr + "/example")
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			s.t.Fatalf("marshal body: %v", err)
+		}
+		s.validate(bodyPtr+"/schema", raw, "request of "+method+" "+path)
+		body = raw
+	}
+
+	httpReq := httptest.NewRequest(method, target, bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if path == "/webhooks/github" {
+		httpReq.Header.Set("X-Hub-Signature-256", signGitHub(body))
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httpReq)
+
+	if rec.Code != wantStatus {
+		s.t.Fatalf("%s %s: status %d, want %d: %s", method, target, rec.Code, wantStatus, rec.Body.String())
+	}
+
+	respPtr := opPtr + "/responses/" + fmt.Sprint(rec.Code)
+	if s.lookup(respPtr) == nil {
+		s.t.Fatalf("%s %s: status %d is not documented", method, path, rec.Code)
+	}
+	respPtr = s.resolve(respPtr)
+	if s.lookup(respPtr+"/content") == nil {
+		if rec.Body.Len() != 0 {
+			s.t.Fatalf("%s %s: undocumented response body %s", method, path, rec.Body.String())
+		}
+		return nil
+	}
+	if s.lookup(respPtr+jsonContentPtrSeg) == nil {
+		return nil
+	}
+
+	s.validate(respPtr+jsonContentPtrSeg+"/schema", rec.Body.Bytes(), fmt.Sprintf("%d response of %s %s", rec.Code, method, path))
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	return resp
+}
+
+func (s *specTester) validate(schemaPtr string, raw []byte, what string) {
+	s.t.Helper()
+	schema, err := s.compiler.Compile(specResourceName + "#" + schemaPtr)
+	if err != nil {
+		s.t.Fatalf("compile %s: %v", schemaPtr, err)
+	}
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		s.t.Fatalf("%s is not JSON: %v", what, err)
+	}
+	if err := schema.Validate(value); err != nil {
+		s.t.Fatalf("%s does not match the spec: %v\n%s", what, err, raw)
+	}
+}
+
+func (s *specTester) exampleQuery(opPtr string, op map[string]any) url.Values {
+	query := url.Values{}
+	params, _ := op["parameters"].([]any)
+	for i := range params {
+		param := s.lookup(s.resolve(fmt.Sprintf("%s/parameters/%d", opPtr, i))).(map[string]any)
+		if param["in"] == "query" && param["example"] != nil {
+			query.Set(param["name"].(string), fmt.Sprint(param["example"]))
+		}
+	}
+	return query
+}
+
+// resolve follows $ref of the object at ptr and returns the pointer of the
+// referenced object.
+func (s *specTester) resolve(ptr string) string {
+	for {
+		obj, _ := s.lookup(ptr).(map[string]any)
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return ptr
+		}
+		ptr = strings.TrimPrefix(ref, "#")
+	}
+}
+
+func (s *specTester) lookup(ptr string) any {
+	var node any = s.doc
+	for _, tok := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[tok]
+		case []any:
+			var i int
+			if _, err := fmt.Sscan(tok, &i); err != nil || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+func (s *specTester) operations() []string {
+	var ops []string
+	for path, item := range s.doc["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			if method != "parameters" {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func signGitHub(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testGitHubSecret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func reviewers(t *testing.T, resp map[string]any) []string {
+	t.Helper()
+	pr, _ := resp["pr"].(map[string]any)
+	raw, _ := pr["assigned_reviewers"].([]any)
+	ids := make([]string, len(raw))
+	for i, id := range raw {
+		ids[i] = id.(string)
+	}
+	if len(ids) == 0 {
+		t.Fatalf("expected assigned reviewers in %v", resp)
+	}
+	return ids
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	router := newTestRouter(t)
+	s := newSpecTester(t, router)
+
+	var routes []string
+	err := chi.Walk(router, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// The Swagger UI page is not part of the API.
+		if route != "/swagger" {
+			routes = append(routes, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+	sort.Strings(routes)
+
+	if got, want := strings.Join(routes, "\n"), strings.Join(s.operations(), "\n"); got != want {
+		t.Fatalf("router and spec differ\nrouter:\n%s\n\nspec:\n%s", got, want)
+	}
+}
+
+func TestAPIConformsToSpec(t *testing.T) {
+	s := newSpecTester(t, newTestRouter(t))
+	admin := http.Header{"X-Admin-Token": {testAdminToken}}
+
+	s.replay("POST", "/team/add", replayRequest{}, http.StatusCreated)
+	s.replay("GET", "/team/get", replayRequest{}, http.StatusOK)
+	s.replay("GET", "/team/settings", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/team/settings", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/team/addMembers", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/team/removeMembers", replayRequest{}, http.StatusOK)
+	planned := s.replay("PUT", "/team/sync", replayRequest{}, http.StatusOK)
+	if removed, _ := planned["removed"].([]any); len(removed) != 1 || removed[0] != "u4" {
+		t.Fatalf("sync plan %v, want u4 removed", planned)
+	}
+	s.replay("POST", "/identities/set", replayRequest{}, http.StatusOK)
+
+	created := s.replay("POST", "/pullRequest/create", replayRequest{}, http.StatusCreated)
+	if team := created["pr"].(map[string]any)["team_name"]; team != "backend" {
+		t.Fatalf("PR team = %v, want the author's team backend", team)
+	}
+	old := reviewers(t, created)[0]
+	reassigned := s.replay("POST", "/pullRequest/reassign", replayRequest{
+		body: map[string]any{"pull_request_id": "pr-1001", "old_user_id": old},
+	}, http.StatusOK)
+	reviewer := reassigned["replaced_by"].(string)
+	s.replay("POST", "/pullRequest/review", replayRequest{
+		body: map[string]any{"pull_request_id": "pr-1001", "reviewer_id": reviewer, "state": models.ReviewApproved},
+	}, http.StatusOK)
+	s.replay("GET", "/users/getReview", replayRequest{query: url.Values{"user_id": {reviewer}}}, http.StatusOK)
+	s.replay("POST", "/pullRequest/close", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/pullRequest/reopen", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/pullRequest/merge", replayRequest{header: admin}, http.StatusOK)
+
+	s.replay("POST", "/pullRequest/create", replayRequest{
+		body: map[string]any{"pull_request_id": "pr-1002", "pull_request_name": "Draft work", "author_id": "u2", "draft": true},
+	}, http.StatusCreated)
+	s.replay("POST", "/pullRequest/markReady", replayRequest{body: map[string]any{"pull_request_id": "pr-1002"}}, http.StatusOK)
+
+	s.replay("POST", "/team/deactivateUsers", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/users/setIsActive", replayRequest{}, http.StatusOK)
+
+	s.replay("POST", "/webhooks/github", replayRequest{header: http.Header{"X-Github-Event": {"pull_request"}}}, http.StatusOK)
+	s.replay("POST", "/webhooks/gitlab", replayRequest{header: http.Header{
+		"X-Gitlab-Event": {"Merge Request Hook"},
+		"X-Gitlab-Token": {testGitLabToken},
+	}}, http.StatusOK)
+
+	sub := s.replay("POST", "/webhooks/subscriptions", replayRequest{}, http.StatusCreated)
+	id := map[string]string{"id": sub["subscription"].(map[string]any)["id"].(string)}
+	s.replay("GET", "/webhooks/subscriptions", replayRequest{}, http.StatusOK)
+	s.replay("GET", "/webhooks/subscriptions/{id}", replayRequest{pathParams: id}, http.StatusOK)
+	s.replay("PUT", "/webhooks/subscriptions/{id}", replayRequest{pathParams: id}, http.StatusOK)
+	s.replay("DELETE", "/webhooks/subscriptions/{id}", replayRequest{pathParams: id}, http.StatusNoContent)
+
+	s.replay("POST", "/team/add", replayRequest{body: map[string]any{
+		"team_name": "legacy",
+		"members":   []map[string]any{{"user_id": "u6", "username": "Frank", "is_active": true}},
+	}}, http.StatusCreated)
+	s.replay("POST", "/team/archive", replayRequest{}, http.StatusOK)
+	s.replay("POST", "/team/removeMembers", replayRequest{body: map[string]any{"team_name": "legacy", "user_ids": []string{"u6"}}}, http.StatusOK)
+	s.replay("POST", "/team/delete", replayRequest{}, http.StatusNoContent)
+	s.replay("POST", "/team/rename", replayRequest{}, http.StatusOK)
+
+	s.replay("GET", "/health", replayRequest{}, http.StatusOK)
+	s.replay("GET", "/health/live", replayRequest{}, http.StatusOK)
+	s.replay("GET", "/health/ready", replayRequest{}, http.StatusOK)
+	s.replay("GET", "/openapi.json", replayRequest{}, http.StatusOK)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/webhooks/subscriptions/{id}",status="200"} 1`,
+		`prs_created_total 3`,
+		`reviewer_assignments_total{strategy="least_loaded"}`,
+		`reassignments_total{reason="manual"} 1`,
+		`open_reviews{user_id=`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	s.replay("GET", "/metrics", replayRequest{}, http.StatusOK)
+
+	for _, op := range s.operations() {
+		if !s.replayed[op] {
+			t.Errorf("%s has no replayed example", op)
+		}
+	}
+}
+
+func TestAPIErrorsConformToSpec(t *testing.T) {
+	s := newSpecTester(t, newTestRouter(t))
+
+	s.replay("POST", "/team/add", replayRequest{}, http.StatusCreated)
+	s.replay("POST", "/team/add", replayRequest{}, http.StatusBadRequest)
+	s.replay("GET", "/team/get", replayRequest{query: url.Values{"team_name": {"unknown"}}}, http.StatusNotFound)
+	s.replay("POST", "/pullRequest/create", replayRequest{}, http.StatusCreated)
+	s.replay("POST", "/pullRequest/create", replayRequest{}, http.StatusConflict)
+	s.replay("POST", "/pullRequest/merge", replayRequest{}, http.StatusForbidden)
+	s.replay("POST", "/pullRequest/merge", replayRequest{body: map[string]any{"pull_request_id": "pr-1001"}}, http.StatusConflict)
+	s.replay("POST", "/webhooks/gitlab", replayRequest{header: http.Header{"X-Gitlab-Event": {"Merge Request Hook"}}}, http.StatusUnauthorized)
+	s.replay("GET", "/webhooks/subscriptions/{id}", replayRequest{pathParams: map[string]string{"id": "missing"}}, http.StatusNotFound)
+
+	s.replay("POST", "/team/addMembers", replayRequest{body: map[string]any{
+		"team_name": "backend",
+		"members":   []map[string]any{{"user_id": "u1", "username": "Alice", "is_active": true}},
+	}}, http.StatusConflict)
+	s.replay("POST", "/team/rename", replayRequest{body: map[string]any{"team_name": "missing", "new_team_name": "other"}}, http.StatusNotFound)
+	s.replay("POST", "/team/delete", replayRequest{body: map[string]any{"team_name": "backend"}}, http.StatusConflict)
+	s.replay("POST", "/pullRequest/create", replayRequest{
+		body: map[string]any{"pull_request_id": "pr-1003", "pull_request_name": "Elsewhere", "author_id": "u1", "team_name": "frontend"},
+	}, http.StatusNotFound)
+	s.replay("POST", "/team/archive", replayRequest{body: map[string]any{"team_name": "backend"}}, http.StatusOK)
+	s.replay("POST", "/team/archive", replayRequest{body: map[string]any{"team_name": "backend"}}, http.StatusConflict)
+	s.replay("PUT", "/team/sync", replayRequest{body: map[string]any{
+		"team_name": "backend",
+		"members":   []map[string]any{{"user_id": "u9", "username": "Ivan", "is_active": true}},
+	}}, http.StatusConflict)
+	s.replay("POST", "/pullRequest/create", replayRequest{
+		body: map[string]any{"pull_request_id": "pr-1002", "pull_request_name": "Late change", "author_id": "u1"},
+	}, http.StatusConflict)
+
+	drained := newTestAPI(t)
+	drained.health.Drain()
+	newSpecTester(t, newRouter(drained)).replay("GET", "/health/ready", replayRequest{}, http.StatusServiceUnavailable)
+
+	// Bodies that violate the request schema are rejected by the handler as
+	// well; they are sent directly because replay validates request bodies.
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(`{"pull_request_id": ""}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rec.Code)
+	}
+	s.validate("/components/schemas/ErrorResponse", rec.Body.Bytes(), "validation error")
+}
+
+func TestSwaggerUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter(t).ServeHTTP(rec, httptest.NewRequest("GET", "/swagger", nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/openapi.json") {
+		t.Fatalf("unexpected Swagger UI response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestsAreTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	router := newTestRouter(t)
+	send := func(path string, body string, header http.Header) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		maps.Copy(req.Header, header)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: status %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	send("/team/add", `{"team_name":"backend","members":[{"user_id":"u1","username":"Alice","is_active":true},{"user_id":"u2","username":"Bob","is_active":true}]}`, nil)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	send("/pullRequest/create", `{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1"}`,
+		http.Header{"Traceparent": {"00-" + traceID + "-00f067aa0ba902b7-01"}})
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	server, method := spans["POST /pullRequest/create"], spans["PRService.CreatePR"]
+	if server == nil || method == nil || spans["POST /team/add"] == nil || spans["TeamService.CreateTeam"] == nil {
+		t.Fatalf("missing spans, got %v", slices.Collect(maps.Keys(spans)))
+	}
+	if server.SpanContext().TraceID().String() != traceID {
+		t.Errorf("request span does not continue the incoming trace: %s", server.SpanContext().TraceID())
+	}
+	if method.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("PRService.CreatePR is not a child of the request span")
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"pr-reviewer-service/internal/logging"
+	"pr-reviewer-service/internal/models"
+)
+
+var errInvalidBody = models.ErrValidation.WithMessage("invalid request body")
+
+// writeError is the only place where errors are turned into responses.
+// Domain errors are reported with their own code, status and message; any
+// other error is an INTERNAL error whose details only go to the request log.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "internal error", slog.Any("error", err))
+		domainErr = models.ErrInternal
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(domainErr.Status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error: struct {
+			Code    string              `json:"code"`
+			Message string              `json:"message"`
+			Fields  []models.FieldError `json:"fields,omitempty"`
+		}{
+			Code:    domainErr.Code,
+			Message: domainErr.Message,
+			Fields:  domainErr.Fields,
+		},
+	})
+}
+
+func requiredParam(name string) error {
+	return models.ErrValidation.WithMessage(name + " parameter is required")
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"sentinel", models.ErrPRExists, http.StatusConflict, models.ErrorPRExists, "PR already exists"},
+		{"derived", models.ErrPRNotFound, http.StatusNotFound, models.ErrorNotFound, "PR not found"},
+		{"wrapped", fmt.Errorf("merge: %w", models.ErrNotApproved), http.StatusConflict, models.ErrorNotApproved, "PR is not approved"},
+		{"validation", errInvalidBody, http.StatusBadRequest, models.ErrorValidation, "invalid request body"},
+		{"unknown", errors.New("connection refused"), http.StatusInternalServerError, models.ErrorInternal, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			var resp models.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Error.Code != tt.code || resp.Error.Message != tt.message {
+				t.Errorf("error = %+v, want %s %q", resp.Error, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+func TestDerivedErrorsMatchTheirSentinel(t *testing.T) {
+	if !errors.Is(models.ErrPRNotFound, models.ErrNotFound) {
+		t.Error("ErrPRNotFound must match ErrNotFound")
+	}
+	if errors.Is(models.ErrPRNotFound, models.ErrTeamNotFound) {
+		t.Error("ErrPRNotFound must not match ErrTeamNotFound")
+	}
+	if errors.Is(models.ErrNotFound, models.ErrPRNotFound) {
+		t.Error("a sentinel must not match errors derived from it")
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", models.ErrUserNotFound.WithMessage("author not found")), models.ErrUserNotFound) {
+		t.Error("wrapped derived error must match its sentinel")
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// verifyGitHubSignature checks the X-Hub-Signature-256 header, which holds
+// "sha256=" followed by the hex HMAC-SHA256 of the raw body.
+func verifyGitHubSignature(secret string, body []byte, header string) bool {
+	if secret == "" || !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// parseGitHubEvent translates a pull_request delivery into a WebhookEvent.
+// It returns nil for events and actions the service does not track.
+func parseGitHubEvent(eventType string, body []byte) (*service.WebhookEvent, error) {
+	if eventType != "pull_request" {
+		return nil, nil
+	}
+
+	var payload githubPullRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.Repository.FullName == "" || payload.PullRequest.Number == 0 {
+		return nil, fmt.Errorf("payload has no repository or pull request number")
+	}
+
+	event := &service.WebhookEvent{
+		Provider:      models.ProviderGitHub,
+		PullRequestID: fmt.Sprintf("%s#%d", payload.Repository.FullName, payload.PullRequest.Number),
+		Title:         payload.PullRequest.Title,
+		AuthorLogin:   payload.PullRequest.User.Login,
+		SenderLogin:   payload.Sender.Login,
+		Draft:         payload.PullRequest.Draft,
+	}
+
+	switch payload.Action {
+	case "opened":
+		event.Action = service.WebhookActionOpen
+	case "closed":
+		event.Action = service.WebhookActionClose
+		if payload.PullRequest.Merged {
+			event.Action = service.WebhookActionMerge
+		}
+	case "reopened":
+		event.Action = service.WebhookActionReopen
+	case "ready_for_review":
+		event.Action = service.WebhookActionReady
+	default:
+		return nil, nil
+	}
+
+	return event, nil
+}
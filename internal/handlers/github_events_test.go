@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+)
+
+func readGitHubFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "github", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return body
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := readGitHubFixture(t, "pull_request_opened.json")
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"valid", "s3cret", valid, true},
+		{"wrong secret", "other", valid, false},
+		{"missing prefix", "s3cret", hex.EncodeToString(mac.Sum(nil)), false},
+		{"not hex", "s3cret", "sha256=zz", false},
+		{"empty header", "s3cret", "", false},
+		{"secret not configured", "", valid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyGitHubSignature(tt.secret, body, tt.header); got != tt.want {
+				t.Errorf("verifyGitHubSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitHubEvent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		action  string
+		prID    string
+		draft   bool
+	}{
+		{"pull_request_opened.json", service.WebhookActionOpen, "acme/backend#42", false},
+		{"pull_request_opened_draft.json", service.WebhookActionOpen, "acme/backend#43", true},
+		{"pull_request_ready_for_review.json", service.WebhookActionReady, "acme/backend#43", false},
+		{"pull_request_closed.json", service.WebhookActionClose, "acme/backend#42", false},
+		{"pull_request_closed_merged.json", service.WebhookActionMerge, "acme/backend#42", false},
+		{"pull_request_reopened.json", service.WebhookActionReopen, "acme/backend#42", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			event, err := parseGitHubEvent("pull_request", readGitHubFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("parseGitHubEvent() error = %v", err)
+			}
+			if event == nil {
+				t.Fatal("parseGitHubEvent() returned no event")
+			}
+			if event.Action != tt.action || event.PullRequestID != tt.prID || event.Draft != tt.draft {
+				t.Errorf("got action=%s id=%s draft=%v, want action=%s id=%s draft=%v",
+					event.Action, event.PullRequestID, event.Draft, tt.action, tt.prID, tt.draft)
+			}
+			if event.AuthorLogin != "octocat" {
+				t.Errorf("AuthorLogin = %q, want octocat", event.AuthorLogin)
+			}
+		})
+	}
+}
+
+func TestParseGitHubEventIgnoresUntrackedEvents(t *testing.T) {
+	event, err := parseGitHubEvent("pull_request", readGitHubFixture(t, "pull_request_labeled.json"))
+	if err != nil || event != nil {
+		t.Errorf("labeled action: got event=%v err=%v, want nil, nil", event, err)
+	}
+
+	event, err = parseGitHubEvent("push", readGitHubFixture(t, "pull_request_opened.json"))
+	if err != nil || event != nil {
+		t.Errorf("push event: got event=%v err=%v, want nil, nil", event, err)
+	}
+}
+
+// webhookTestEnv wires a WebhookHandler to the real services over the
+// in-memory store, with team backend made of u1, u2 and u3.
+type webhookTestEnv struct {
+	handler *WebhookHandler
+	service *service.WebhookService
+	prs     repository.PRRepo
+}
+
+func newWebhookTestEnv(t *testing.T, mappings ...models.IdentityMapping) *webhookTestEnv {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	repos := store.Repos()
+
+	team := &models.Team{TeamName: "backend"}
+	for _, id := range []string{"u1", "u2", "u3"} {
+		team.Members = append(team.Members, models.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if _, err := service.NewTeamService(store, repos.Team, repos.User).CreateTeam(ctx, team); err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+
+	identities := service.NewIdentityService(store, repos.Identity, repos.User)
+	for _, mapping := range mappings {
+		if _, err := identities.SetMapping(ctx, &mapping); err != nil {
+			t.Fatalf("map %s login %s: %v", mapping.Provider, mapping.Login, err)
+		}
+	}
+
+	prService := service.NewPRService(store, repos.PR, repos.User, repos.Team, models.StrategyRandom, nil)
+	svc := service.NewWebhookService(prService, identities)
+	return &webhookTestEnv{
+		handler: NewWebhookHandler(svc, "s3cret", "t0ken"),
+		service: svc,
+		prs:     repos.PR,
+	}
+}
+
+func (e *webhookTestEnv) pr(t *testing.T, id string) *models.PullRequest {
+	t.Helper()
+	pr, err := e.prs.GetPR(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetPR(%s): %v", id, err)
+	}
+	return pr
+}
+
+// postGitHub sends fixture to the GitHub handler, signed with secret.
+func (e *webhookTestEnv) postGitHub(t *testing.T, fixture string, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := readGitHubFixture(t, fixture)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	e.handler.GitHub(rec, req)
+	return rec
+}
+
+func decodeWebhookResult(t *testing.T, rec *httptest.ResponseRecorder) service.WebhookResult {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var result service.WebhookResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return result
+}
+
+func TestGitHubWebhookDrivesPullRequest(t *testing.T) {
+	env := newWebhookTestEnv(t, models.IdentityMapping{Provider: models.ProviderGitHub, Login: "octocat", UserID: "u1"})
+	const prID = "acme/backend#42"
+
+	if result := decodeWebhookResult(t, env.postGitHub(t, "pull_request_opened.json", "s3cret")); result.Status != "applied" {
+		t.Fatalf("opened: %+v", result)
+	}
+	pr := env.pr(t, prID)
+	reviewers := slices.Sorted(slices.Values(pr.AssignedReviewers))
+	if pr.AuthorID != "u1" || pr.Status != models.PRStatusOpen || !slices.Equal(reviewers, []string{"u2", "u3"}) {
+		t.Fatalf("opened PR = %+v, want u1's OPEN PR reviewed by u2 and u3", pr)
+	}
+
+	// A repeated delivery must not change anything.
+	if result := decodeWebhookResult(t, env.postGitHub(t, "pull_request_opened.json", "s3cret")); result.Status != "ignored" {
+		t.Fatalf("repeated opened: %+v", result)
+	}
+
+	steps := []struct {
+		fixture string
+		result  string
+		status  string
+	}{
+		{"pull_request_closed.json", "applied", models.PRStatusClosed},
+		{"pull_request_reopened.json", "applied", models.PRStatusOpen},
+		// A redelivered reopen finds the PR already OPEN.
+		{"pull_request_reopened.json", "ignored", models.PRStatusOpen},
+		{"pull_request_closed_merged.json", "applied", models.PRStatusMerged},
+	}
+	for _, step := range steps {
+		if result := decodeWebhookResult(t, env.postGitHub(t, step.fixture, "s3cret")); result.Status != step.result {
+			t.Fatalf("%s: %+v, want %s", step.fixture, result, step.result)
+		}
+		if pr := env.pr(t, prID); pr.Status != step.status {
+			t.Fatalf("after %s status = %s, want %s", step.fixture, pr.Status, step.status)
+		}
+	}
+}
+
+func TestGitHubWebhookRejectsBadSignature(t *testing.T) {
+	env := newWebhookTestEnv(t, models.IdentityMapping{Provider: models.ProviderGitHub, Login: "octocat", UserID: "u1"})
+
+	rec := env.postGitHub(t, "pull_request_opened.json", "wrong")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	var resp models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error.Code != models.ErrorInvalidSignature {
+		t.Fatalf("error response = %+v (%v)", resp, err)
+	}
+	if _, err := env.prs.GetPR(context.Background(), "acme/backend#42"); !errors.Is(err, models.ErrPRNotFound) {
+		t.Fatalf("unsigned event must not create the PR, got %v", err)
+	}
+}
+
+func TestGitHubWebhookIgnoresUnknownLogin(t *testing.T) {
+	env := newWebhookTestEnv(t)
+
+	result := decodeWebhookResult(t, env.postGitHub(t, "pull_request_opened.json", "s3cret"))
+	if result.Status != "ignored" || !strings.Contains(result.Reason, `"octocat"`) {
+		t.Fatalf("result = %+v, want ignored because octocat is not mapped", result)
+	}
+	if _, err := env.prs.GetPR(context.Background(), "acme/backend#42"); !errors.Is(err, models.ErrPRNotFound) {
+		t.Fatalf("PR of an unmapped author must not be created, got %v", err)
+	}
+}
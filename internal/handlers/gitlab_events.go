@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+type gitlabMergeRequestEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Action string `json:"action"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Draft *struct {
+			Previous bool `json:"previous"`
+			Current  bool `json:"current"`
+		} `json:"draft"`
+	} `json:"changes"`
+}
+
+func verifyGitLabToken(expected string, header string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(header)) == 1
+}
+
+// parseGitLabEvent translates a Merge Request Hook delivery into a
+// WebhookEvent. It returns nil for events and actions the service does not
+// track. GitLab only sends the numeric author_id, so the user who triggered
+// the event is used as the author of newly opened merge requests.
+func parseGitLabEvent(eventType string, body []byte) (*service.WebhookEvent, error) {
+	if eventType != "Merge Request Hook" {
+		return nil, nil
+	}
+
+	var payload gitlabMergeRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.Project.PathWithNamespace == "" || payload.ObjectAttributes.IID == 0 {
+		return nil, fmt.Errorf("payload has no project or merge request iid")
+	}
+
+	event := &service.WebhookEvent{
+		Provider:      models.ProviderGitLab,
+		PullRequestID: fmt.Sprintf("%s!%d", payload.Project.PathWithNamespace, payload.ObjectAttributes.IID),
+		Title:         payload.ObjectAttributes.Title,
+		AuthorLogin:   payload.User.Username,
+		SenderLogin:   payload.User.Username,
+		Draft:         payload.ObjectAttributes.Draft,
+	}
+
+	switch payload.ObjectAttributes.Action {
+	case "open":
+		event.Action = service.WebhookActionOpen
+	case "update":
+		draft := payload.Changes.Draft
+		if draft == nil || !draft.Previous || draft.Current {
+			return nil, nil
+		}
+		event.Action = service.WebhookActionReady
+	case "merge":
+		event.Action = service.WebhookActionMerge
+	case "close":
+		event.Action = service.WebhookActionClose
+	case "reopen":
+		event.Action = service.WebhookActionReopen
+	case "approved":
+		event.Action = service.WebhookActionApprove
+	default:
+		return nil, nil
+	}
+
+	return event, nil
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+func readGitLabFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "gitlab", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return body
+}
+
+func TestVerifyGitLabToken(t *testing.T) {
+	if !verifyGitLabToken("t0ken", "t0ken") {
+		t.Error("matching token rejected")
+	}
+	if verifyGitLabToken("t0ken", "other") {
+		t.Error("wrong token accepted")
+	}
+	if verifyGitLabToken("", "") {
+		t.Error("empty token accepted when none is configured")
+	}
+}
+
+func TestParseGitLabEvent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		action  string
+		prID    string
+		sender  string
+		draft   bool
+	}{
+		{"merge_request_open.json", service.WebhookActionOpen, "acme/payments!7", "jdoe", false},
+		{"merge_request_open_draft.json", service.WebhookActionOpen, "acme/payments!8", "jdoe", true},
+		{"merge_request_update_ready.json", service.WebhookActionReady, "acme/payments!8", "jdoe", false},
+		{"merge_request_merge.json", service.WebhookActionMerge, "acme/payments!7", "mmaintainer", false},
+		{"merge_request_close.json", service.WebhookActionClose, "acme/payments!7", "mmaintainer", false},
+		{"merge_request_reopen.json", service.WebhookActionReopen, "acme/payments!7", "mmaintainer", false},
+		{"merge_request_approved.json", service.WebhookActionApprove, "acme/payments!7", "rreviewer", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			event, err := parseGitLabEvent("Merge Request Hook", readGitLabFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("parseGitLabEvent() error = %v", err)
+			}
+			if event == nil {
+				t.Fatal("parseGitLabEvent() returned no event")
+			}
+			if event.Action != tt.action || event.PullRequestID != tt.prID || event.SenderLogin != tt.sender || event.Draft != tt.draft {
+				t.Errorf("got action=%s id=%s sender=%s draft=%v, want action=%s id=%s sender=%s draft=%v",
+					event.Action, event.PullRequestID, event.SenderLogin, event.Draft, tt.action, tt.prID, tt.sender, tt.draft)
+			}
+		})
+	}
+}
+
+func TestParseGitLabEventIgnoresUntrackedEvents(t *testing.T) {
+	event, err := parseGitLabEvent("Merge Request Hook", readGitLabFixture(t, "merge_request_update_title.json"))
+	if err != nil || event != nil {
+		t.Errorf("title update: got event=%v err=%v, want nil, nil", event, err)
+	}
+
+	event, err = parseGitLabEvent("Push Hook", readGitLabFixture(t, "merge_request_open.json"))
+	if err != nil || event != nil {
+		t.Errorf("push hook: got event=%v err=%v, want nil, nil", event, err)
+	}
+}
+
+// applyGitLab parses fixture and runs it through WebhookService.Apply.
+func (e *webhookTestEnv) applyGitLab(t *testing.T, fixture string) *service.WebhookResult {
+	t.Helper()
+	event, err := parseGitLabEvent("Merge Request Hook", readGitLabFixture(t, fixture))
+	if err != nil || event == nil {
+		t.Fatalf("parse %s: event=%v err=%v", fixture, event, err)
+	}
+	result, err := e.service.Apply(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Apply(%s): %v", fixture, err)
+	}
+	return result
+}
+
+func TestGitLabWebhookDrivesMergeRequest(t *testing.T) {
+	env := newWebhookTestEnv(t,
+		models.IdentityMapping{Provider: models.ProviderGitLab, Login: "jdoe", UserID: "u1"},
+		models.IdentityMapping{Provider: models.ProviderGitLab, Login: "rreviewer", UserID: "u2"},
+	)
+	const prID = "acme/payments!7"
+
+	if result := env.applyGitLab(t, "merge_request_open.json"); result.Status != "applied" {
+		t.Fatalf("open: %+v", result)
+	}
+	pr := env.pr(t, prID)
+	if pr.AuthorID != "u1" || pr.Status != models.PRStatusOpen || len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("opened MR = %+v, want u1's OPEN PR with two reviewers", pr)
+	}
+
+	if result := env.applyGitLab(t, "merge_request_approved.json"); result.Status != "applied" {
+		t.Fatalf("approved: %+v", result)
+	}
+	approved := false
+	for _, review := range env.pr(t, prID).Reviews {
+		approved = approved || (review.ReviewerID == "u2" && review.State == models.ReviewApproved)
+	}
+	if !approved {
+		t.Fatalf("rreviewer's approval is not recorded for u2: %+v", env.pr(t, prID).Reviews)
+	}
+
+	steps := []struct {
+		fixture string
+		result  string
+		status  string
+	}{
+		{"merge_request_close.json", "applied", models.PRStatusClosed},
+		{"merge_request_reopen.json", "applied", models.PRStatusOpen},
+		// A redelivered reopen finds the MR already OPEN.
+		{"merge_request_reopen.json", "ignored", models.PRStatusOpen},
+		{"merge_request_merge.json", "applied", models.PRStatusMerged},
+	}
+	for _, step := range steps {
+		if result := env.applyGitLab(t, step.fixture); result.Status != step.result {
+			t.Fatalf("%s: %+v, want %s", step.fixture, result, step.result)
+		}
+		if pr := env.pr(t, prID); pr.Status != step.status {
+			t.Fatalf("after %s status = %s, want %s", step.fixture, pr.Status, step.status)
+		}
+	}
+}
+
+func TestGitLabWebhookMarksDraftReadyOnce(t *testing.T) {
+	env := newWebhookTestEnv(t, models.IdentityMapping{Provider: models.ProviderGitLab, Login: "jdoe", UserID: "u1"})
+	const prID = "acme/payments!8"
+
+	if result := env.applyGitLab(t, "merge_request_open_draft.json"); result.Status != "applied" {
+		t.Fatalf("open draft: %+v", result)
+	}
+	if pr := env.pr(t, prID); pr.Status != models.PRStatusDraft || len(pr.AssignedReviewers) != 0 {
+		t.Fatalf("draft MR = %+v, want a DRAFT PR without reviewers", pr)
+	}
+
+	if result := env.applyGitLab(t, "merge_request_update_ready.json"); result.Status != "applied" {
+		t.Fatalf("ready: %+v", result)
+	}
+	ready := env.pr(t, prID)
+	if ready.Status != models.PRStatusOpen || len(ready.AssignedReviewers) != 2 {
+		t.Fatalf("ready MR = %+v, want an OPEN PR with two reviewers", ready)
+	}
+
+	// A repeated delivery must not fail or reassign the reviewers.
+	result := env.applyGitLab(t, "merge_request_update_ready.json")
+	if result.Status != "ignored" {
+		t.Fatalf("repeated ready: %+v", result)
+	}
+	if pr := env.pr(t, prID); pr.Status != models.PRStatusOpen || !slices.Equal(pr.AssignedReviewers, ready.AssignedReviewers) {
+		t.Fatalf("after repeated ready MR = %+v, want it unchanged from %+v", pr, ready)
+	}
+}
+
+func TestGitLabWebhookRejectsWrongToken(t *testing.T) {
+	env := newWebhookTestEnv(t, models.IdentityMapping{Provider: models.ProviderGitLab, Login: "jdoe", UserID: "u1"})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(readGitLabFixture(t, "merge_request_open.json")))
+	req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+	req.Header.Set("X-Gitlab-Token", "wrong")
+	rec := httptest.NewRecorder()
+	env.handler.GitLab(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	var resp models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error.Code != models.ErrorInvalidSignature {
+		t.Fatalf("error response = %+v (%v)", resp, err)
+	}
+	if _, err := env.prs.GetPR(context.Background(), "acme/payments!7"); !errors.Is(err, models.ErrPRNotFound) {
+		t.Fatalf("rejected event must not create the MR, got %v", err)
+	}
+}
@@ -1,34 +1,120 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"pr-reviewer-service/internal/models"
 )
 
-type HealthHandler struct{}
+// healthCheckTimeout bounds each readiness check, so a hung dependency makes
+// the probe fail instead of time out.
+const healthCheckTimeout = 2 * time.Second
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// HealthCheck is one dependency reported by the readiness probe. Check
+// returns details to include in the response and an error when the
+// dependency is unusable.
+// OutboxCheckName names the check whose details the deprecated /health
+// endpoint reports as the outbox backlog.
+const OutboxCheckName = "outbox_relay"
+
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) (any, error)
+}
+
+type HealthHandler struct {
+	checks   []HealthCheck
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
 }
 
+// Drain makes the readiness probe fail from now on, so load balancers stop
+// sending requests before the server shuts down.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and serving HTTP. It checks no
+// dependencies: a database outage must not get the service restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// Health serves the deprecated /health endpoint for probes that predate
+// the split: like Live it answers 200 while the process serves HTTP, and it
+// adds the outbox backlog from the OutboxCheckName check.
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"status": "ok", "outbox": map[string]string{"error": "unavailable"}}
+	for _, check := range h.checks {
+		if check.Name != OutboxCheckName {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		details, err := check.Check(ctx)
+		cancel()
+		if err == nil && details != nil {
+			resp["outbox"] = details
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(resp)
 }
 
-func writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+// Ready runs every check concurrently and answers 503 unless all of them
+// pass and the server is not shutting down.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	components := make([]models.ComponentHealth, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+
+			details, err := check.Check(ctx)
+			components[i] = models.ComponentHealth{Status: models.HealthUp, Details: details}
+			if err != nil {
+				components[i].Status = models.HealthDown
+				components[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp := models.ReadinessResponse{
+		Status:     models.HealthReady,
+		Components: make(map[string]models.ComponentHealth, len(h.checks)+1),
+	}
+	for i, check := range h.checks {
+		resp.Components[check.Name] = components[i]
+	}
+	resp.Components["shutdown"] = models.ComponentHealth{Status: models.HealthUp}
+	if h.draining.Load() {
+		resp.Components["shutdown"] = models.ComponentHealth{Status: models.HealthDown, Error: "server is shutting down"}
+	}
+
+	status := http.StatusOK
+	for _, component := range resp.Components {
+		if component.Status != models.HealthUp {
+			resp.Status = models.HealthNotReady
+			status = http.StatusServiceUnavailable
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(models.ErrorResponse{
-		Error: struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		}{
-			Code:    code,
-			Message: message,
-		},
-	})
-}
\ No newline at end of file
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
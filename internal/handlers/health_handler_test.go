@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+)
+
+func readiness(t *testing.T, h *HealthHandler) (int, models.ReadinessResponse) {
+	t.Helper()
+	// A deadline shorter than healthCheckTimeout keeps the hung check short.
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/health/ready", nil))
+
+	var resp models.ReadinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestReadiness(t *testing.T) {
+	up := HealthCheck{Name: "database", Check: func(context.Context) (any, error) { return nil, nil }}
+	down := HealthCheck{Name: "migrations", Check: func(context.Context) (any, error) {
+		return map[string]int64{"version": 3, "latest": 4}, errors.New("version 4 is pending")
+	}}
+	hung := HealthCheck{Name: "outbox_relay", Check: func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	status, resp := readiness(t, NewHealthHandler(up))
+	if status != http.StatusOK || resp.Status != models.HealthReady || resp.Components["database"].Status != models.HealthUp {
+		t.Fatalf("healthy service: %d %+v", status, resp)
+	}
+
+	status, resp = readiness(t, NewHealthHandler(up, down, hung))
+	if status != http.StatusServiceUnavailable || resp.Status != models.HealthNotReady {
+		t.Fatalf("failing check: %d %+v", status, resp)
+	}
+	if c := resp.Components["migrations"]; c.Status != models.HealthDown || c.Error != "version 4 is pending" || c.Details == nil {
+		t.Errorf("migrations = %+v", c)
+	}
+	if c := resp.Components["outbox_relay"]; c.Status != models.HealthDown || c.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("a hung check must time out, got %+v", c)
+	}
+	if resp.Components["database"].Status != models.HealthUp || resp.Components["shutdown"].Status != models.HealthUp {
+		t.Errorf("healthy components must be reported as up: %+v", resp.Components)
+	}
+}
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	h := NewHealthHandler()
+	h.Drain()
+
+	status, resp := readiness(t, h)
+	if status != http.StatusServiceUnavailable || resp.Components["shutdown"].Status != models.HealthDown {
+		t.Fatalf("draining service: %d %+v", status, resp)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("liveness must not fail while draining, got %d", rec.Code)
+	}
+}
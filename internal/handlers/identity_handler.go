@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+type IdentityHandler struct {
+	service *service.IdentityService
+}
+
+func NewIdentityHandler(svc *service.IdentityService) *IdentityHandler {
+	return &IdentityHandler{service: svc}
+}
+
+func (h *IdentityHandler) SetMapping(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Provider string `json:"provider" validate:"required,max=32"`
+		Login    string `json:"login" validate:"required,max=255"`
+		UserID   string `json:"user_id" validate:"required,max=255,id"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.SetMapping(r.Context(), &models.IdentityMapping{
+		Provider: req.Provider,
+		Login:    req.Login,
+		UserID:   req.UserID,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"identity": result})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 
@@ -9,57 +10,64 @@ import (
 )
 
 type PRHandler struct {
-	service *service.PRService
+	service    *service.PRService
+	adminToken string
 }
 
-func NewPRHandler(svc *service.PRService) *PRHandler {
-	return &PRHandler{service: svc}
+func NewPRHandler(svc *service.PRService, adminToken string) *PRHandler {
+	return &PRHandler{service: svc, adminToken: adminToken}
 }
 
 func (h *PRHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PRId     string `json:"pull_request_id"`
-		PRName   string `json:"pull_request_name"`
-		AuthorId string `json:"author_id"`
+		PRId     string `json:"pull_request_id" validate:"required,max=255,id"`
+		PRName   string `json:"pull_request_name" validate:"required,max=255"`
+		AuthorId string `json:"author_id" validate:"required,max=255,id"`
+		TeamName string `json:"team_name" validate:"max=255"`
+		Draft    bool   `json:"draft"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "Invalid request body")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	pr, err := h.service.CreatePR(r.Context(), req.PRId, req.PRName, req.AuthorId)
+	pr, warning, err := h.service.CreatePR(r.Context(), req.PRId, req.PRName, req.AuthorId, req.TeamName, req.Draft)
 	if err != nil {
-		if err.Error() == "author not found" {
-			writeError(w, http.StatusNotFound, models.ErrorNotFound, "resource not found")
-			return
-		}
-		if err.Error() == "PR already exists" {
-			writeError(w, http.StatusConflict, models.ErrorPRExists, "PR id already exists")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, models.ErrorNotFound, "Internal server error")
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
+
+	resp := map[string]interface{}{"pr": pr}
+	if warning != "" {
+		resp["warning"] = warning
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PRId string `json:"pull_request_id"`
+		PRId     string `json:"pull_request_id" validate:"required,max=255,id"`
+		Force    bool   `json:"force"`
+		MergedBy string `json:"merged_by" validate:"max=255"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "Invalid request body")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	pr, err := h.service.MergePR(r.Context(), req.PRId)
+	if req.Force && !h.isAdmin(r) {
+		writeError(w, r, models.ErrForbidden.WithMessage("force merge requires a valid X-Admin-Token"))
+		return
+	}
+
+	pr, err := h.service.MergePR(r.Context(), req.PRId, req.Force, req.MergedBy)
 	if err != nil {
-		writeError(w, http.StatusNotFound, models.ErrorNotFound, "resource not found")
+		writeError(w, r, err)
 		return
 	}
 
@@ -68,29 +76,25 @@ func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
 }
 
+func (h *PRHandler) isAdmin(r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
 func (h *PRHandler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PRId      string `json:"pull_request_id"`
-		OldUserId string `json:"old_user_id"`
+		PRId      string `json:"pull_request_id" validate:"required,max=255,id"`
+		OldUserId string `json:"old_user_id" validate:"required,max=255,id"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "Invalid request body")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	pr, newReviewerId, err := h.service.ReassignReviewer(r.Context(), req.PRId, req.OldUserId)
 	if err != nil {
-		switch err.Error() {
-		case "PR is merged":
-			writeError(w, http.StatusConflict, models.ErrorPRMerged, "cannot reassign on merged PR")
-		case "reviewer is not assigned":
-			writeError(w, http.StatusConflict, models.ErrorNotAssigned, "reviewer is not assigned to this PR")
-		case "no active replacement candidate in team":
-			writeError(w, http.StatusConflict, models.ErrorNoCandidate, "no active replacement candidate in team")
-		default:
-			writeError(w, http.StatusNotFound, models.ErrorNotFound, "resource not found")
-		}
+		writeError(w, r, err)
 		return
 	}
 
@@ -100,4 +104,97 @@ func (h *PRHandler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		"pr":          pr,
 		"replaced_by": newReviewerId,
 	})
-}
\ No newline at end of file
+}
+
+func (h *PRHandler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PRId       string `json:"pull_request_id" validate:"required,max=255,id"`
+		ReviewerId string `json:"reviewer_id" validate:"required,max=255,id"`
+		State      string `json:"state" validate:"required"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	pr, err := h.service.SubmitReview(r.Context(), req.PRId, req.ReviewerId, req.State)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
+}
+
+func (h *PRHandler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PRId string `json:"pull_request_id" validate:"required,max=255,id"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	pr, err := h.service.ClosePR(r.Context(), req.PRId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
+}
+
+func (h *PRHandler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PRId string `json:"pull_request_id" validate:"required,max=255,id"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	pr, warning, err := h.service.ReopenPR(r.Context(), req.PRId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePRWithWarning(w, pr, warning)
+}
+
+func (h *PRHandler) MarkReady(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PRId string `json:"pull_request_id" validate:"required,max=255,id"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	pr, warning, err := h.service.MarkReady(r.Context(), req.PRId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePRWithWarning(w, pr, warning)
+}
+
+func writePRWithWarning(w http.ResponseWriter, pr *models.PullRequest, warning string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := map[string]interface{}{"pr": pr}
+	if warning != "" {
+		resp["warning"] = warning
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SubscriptionHandler struct {
+	service *service.SubscriptionService
+}
+
+func NewSubscriptionHandler(svc *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: svc}
+}
+
+type subscriptionRequest struct {
+	URL      string   `json:"url" validate:"required"`
+	Secret   string   `json:"secret" validate:"max=255"`
+	Events   []string `json:"events" validate:"max=64,unique"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// subscription builds the subscription from the request. A missing
+// is_active means active, as in the database.
+func (req *subscriptionRequest) subscription() *models.WebhookSubscription {
+	sub := &models.WebhookSubscription{
+		URL:      req.URL,
+		Secret:   req.Secret,
+		Events:   req.Events,
+		IsActive: true,
+	}
+	if req.IsActive != nil {
+		sub.IsActive = *req.IsActive
+	}
+	return sub
+}
+
+func (h *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionRequest
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.CreateSubscription(r.Context(), req.subscription())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscription": result})
+}
+
+func (h *SubscriptionHandler) List(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListSubscriptions(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": subs})
+}
+
+func (h *SubscriptionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	sub, err := h.service.GetSubscription(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscription": sub})
+}
+
+func (h *SubscriptionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionRequest
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	sub := req.subscription()
+	sub.ID = chi.URLParam(r, "id")
+
+	result, err := h.service.UpdateSubscription(r.Context(), sub)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscription": result})
+}
+
+func (h *SubscriptionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteSubscription(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+)
+
+func TestCreateSubscriptionIsActiveByDefault(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, r.Header.Get("X-Webhook-Delivery"))
+	}))
+	defer receiver.Close()
+
+	store := repository.NewMemoryStore()
+	subs := store.Repos().Subscription
+	handler := NewSubscriptionHandler(service.NewSubscriptionService(store, subs))
+
+	body := `{"url": "` + receiver.URL + `", "events": ["pr.created"]}`
+	rec := httptest.NewRecorder()
+	handler.Create(rec, httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Subscription models.WebhookSubscription `json:"subscription"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Subscription.IsActive {
+		t.Fatalf("subscription created without is_active = %+v, want active", resp.Subscription)
+	}
+
+	dispatcher := service.NewWebhookDispatcher(subs, receiver.Client(), service.DefaultRetryPolicy)
+	event := &models.Event{ID: "e1", Type: models.EventPRCreated, OccurredAt: time.Now().UTC(), Data: []byte(`{}`)}
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != "e1" {
+		t.Fatalf("delivered = %v, want the event to reach the new subscription", delivered)
+	}
+}
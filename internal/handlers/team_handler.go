@@ -9,28 +9,47 @@ import (
 )
 
 type TeamHandler struct {
-	service *service.TeamService
+	service   *service.TeamService
+	prService *service.PRService
 }
 
-func NewTeamHandler(svc *service.TeamService) *TeamHandler {
-	return &TeamHandler{service: svc}
+func NewTeamHandler(svc *service.TeamService, prSvc *service.PRService) *TeamHandler {
+	return &TeamHandler{service: svc, prService: prSvc}
+}
+
+type teamMemberRequest struct {
+	UserID   string `json:"user_id" validate:"required,max=255,id"`
+	Username string `json:"username" validate:"required,max=255"`
+	IsActive bool   `json:"is_active"`
+	Role     string `json:"role" validate:"max=32"`
+}
+
+type createTeamRequest struct {
+	TeamName         string              `json:"team_name" validate:"required,max=255"`
+	Members          []teamMemberRequest `json:"members" validate:"unique=user_id"`
+	ReviewerStrategy string              `json:"reviewer_strategy" validate:"max=32"`
 }
 
 func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
-	var team models.Team
+	var req createTeamRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "Invalid request body")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	team := models.Team{
+		TeamName:         req.TeamName,
+		Members:          make([]models.TeamMember, len(req.Members)),
+		ReviewerStrategy: req.ReviewerStrategy,
+	}
+	for i, m := range req.Members {
+		team.Members[i] = models.TeamMember(m)
+	}
+
 	result, err := h.service.CreateTeam(r.Context(), &team)
 	if err != nil {
-		if err.Error() == "team already exists" {
-			writeError(w, http.StatusBadRequest, models.ErrorTeamExists, "team_name already exists")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, models.ErrorNotFound, "Internal server error")
+		writeError(w, r, err)
 		return
 	}
 
@@ -42,17 +61,226 @@ func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
 	teamName := r.URL.Query().Get("team_name")
 	if teamName == "" {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "team_name parameter is required")
+		writeError(w, r, requiredParam("team_name"))
 		return
 	}
 
 	team, err := h.service.GetTeam(r.Context(), teamName)
 	if err != nil {
-		writeError(w, http.StatusNotFound, models.ErrorNotFound, "resource not found")
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(team)
-}
\ No newline at end of file
+}
+
+func (h *TeamHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, r, requiredParam("team_name"))
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), teamName)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"settings": settings})
+}
+
+func (h *TeamHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName               string  `json:"team_name" validate:"required,max=255"`
+		ReviewerStrategy       *string `json:"reviewer_strategy"`
+		ReviewersPerPR         *int    `json:"reviewers_per_pr"`
+		MinReviewers           *int    `json:"min_reviewers"`
+		AllowFewerThanRequired *bool   `json:"allow_fewer_than_required"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.UpdateSettings(r.Context(), req.TeamName, models.TeamSettingsUpdate{
+		ReviewerStrategy:       req.ReviewerStrategy,
+		ReviewersPerPR:         req.ReviewersPerPR,
+		MinReviewers:           req.MinReviewers,
+		AllowFewerThanRequired: req.AllowFewerThanRequired,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"settings": result})
+}
+
+func (h *TeamHandler) DeactivateUsers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string   `json:"team_name" validate:"required,max=255"`
+		UserIDs  []string `json:"user_ids" validate:"required,max=255,id,unique"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.prService.BulkDeactivateTeamUsers(r.Context(), req.TeamName, req.UserIDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *TeamHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string              `json:"team_name" validate:"required,max=255"`
+		Members  []teamMemberRequest `json:"members" validate:"required,unique=user_id"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	members := make([]models.TeamMember, len(req.Members))
+	for i, m := range req.Members {
+		members[i] = models.TeamMember(m)
+	}
+
+	team, err := h.service.AddMembers(r.Context(), req.TeamName, members)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"team": team})
+}
+
+func (h *TeamHandler) RemoveMembers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string   `json:"team_name" validate:"required,max=255"`
+		UserIDs  []string `json:"user_ids" validate:"required,max=255,id,unique"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.prService.RemoveTeamMembers(r.Context(), req.TeamName, req.UserIDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *TeamHandler) RenameTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName    string `json:"team_name" validate:"required,max=255"`
+		NewTeamName string `json:"new_team_name" validate:"required,max=255"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	team, err := h.service.RenameTeam(r.Context(), req.TeamName, req.NewTeamName)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"team": team})
+}
+
+func (h *TeamHandler) ArchiveTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName   string `json:"team_name" validate:"required,max=255"`
+		ReassignTo string `json:"reassign_to" validate:"max=255"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.prService.ArchiveTeam(r.Context(), req.TeamName, req.ReassignTo)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name" validate:"required,max=255"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.service.DeleteTeam(r.Context(), req.TeamName); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TeamHandler) SyncTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string              `json:"team_name" validate:"required,max=255"`
+		Members  []teamMemberRequest `json:"members" validate:"unique=user_id"`
+		DryRun   bool                `json:"dry_run"`
+	}
+
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	roster := make([]models.TeamMember, len(req.Members))
+	for i, m := range req.Members {
+		roster[i] = models.TeamMember(m)
+	}
+
+	result, err := h.prService.SyncTeam(r.Context(), req.TeamName, roster, req.DryRun)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"pr-reviewer-service/internal/models"
 	"pr-reviewer-service/internal/service"
 )
 
@@ -18,18 +17,18 @@ func NewUserHandler(svc *service.UserService) *UserHandler {
 
 func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		UserID   string `json:"user_id"`
-		IsActive bool   `json:"is_active"`
+		UserID   string `json:"user_id" validate:"required,max=255,id"`
+		IsActive *bool  `json:"is_active" validate:"required"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "Invalid request body")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.service.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	user, err := h.service.SetIsActive(r.Context(), req.UserID, *req.IsActive)
 	if err != nil {
-		writeError(w, http.StatusNotFound, models.ErrorNotFound, "resource not found")
+		writeError(w, r, err)
 		return
 	}
 
@@ -41,20 +40,20 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		writeError(w, http.StatusBadRequest, models.ErrorNotFound, "user_id parameter is required")
+		writeError(w, r, requiredParam("user_id"))
 		return
 	}
 
 	prs, err := h.service.GetUserReviews(r.Context(), userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, models.ErrorNotFound, "Internal server error")
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"user_id":        userID,
+		"user_id":       userID,
 		"pull_requests": prs,
 	})
-}
\ No newline at end of file
+}
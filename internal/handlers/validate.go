@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"pr-reviewer-service/internal/models"
+)
+
+// maxBodyBytes bounds request bodies; the largest legitimate body is a team
+// roster, which stays far below it.
+const maxBodyBytes = 1 << 20
+
+// idChars are the characters allowed in user and pull request IDs besides
+// letters and digits. "/", "#" and "!" appear in IDs created from GitHub and
+// GitLab webhooks ("org/repo#12", "group/project!7").
+const idChars = "._-:/#!@"
+
+// decodeJSON decodes the request body into dst and validates it. Unknown
+// fields, trailing data and values of the wrong type are validation errors.
+//
+// Request structs declare their rules in a `validate` tag:
+//
+//	required    string is not blank, slice is not empty, pointer is not nil
+//	max=N       string has at most N characters
+//	id          string consists of letters, digits and idChars
+//	unique      []string has no duplicates
+//	unique=F    []struct has no two elements with the same JSON field F
+//
+// On []string fields max and id apply to every element. Nested structs and
+// slices of structs are validated recursively.
+func decodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return errInvalidBody
+	}
+
+	return validate(dst)
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return models.ErrValidation.WithFields([]models.FieldError{
+			{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)},
+		})
+	}
+
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return models.ErrValidation.WithFields([]models.FieldError{
+			{Field: strings.Trim(field, `"`), Message: "is not allowed"},
+		})
+	}
+
+	return errInvalidBody
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	}
+	return "an object"
+}
+
+// validate checks the `validate` tags of the struct v points to and returns
+// a VALIDATION_ERROR listing every offending field, or nil.
+func validate(v any) error {
+	var fields []models.FieldError
+	validateStruct(reflect.Indirect(reflect.ValueOf(v)), "", &fields)
+	if len(fields) > 0 {
+		return models.ErrValidation.WithFields(fields)
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *[]models.FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		validateField(v.Field(i), prefix+jsonName(sf), sf.Tag.Get("validate"), errs)
+	}
+}
+
+func validateField(v reflect.Value, path string, tag string, errs *[]models.FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, models.FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, rule := range splitRules(tag) {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if isBlank(v) {
+				fail("is required")
+				return
+			}
+		case "max":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				panic(fmt.Sprintf("handlers: bad max rule on %s: %q", path, rule))
+			}
+			eachString(v, path, func(s string, p string) {
+				if utf8.RuneCountInString(s) > limit {
+					*errs = append(*errs, models.FieldError{Field: p, Message: fmt.Sprintf("must be at most %d characters", limit)})
+				}
+			})
+		case "id":
+			eachString(v, path, func(s string, p string) {
+				if s != "" && !isID(s) {
+					*errs = append(*errs, models.FieldError{Field: p, Message: "must contain only letters, digits and " + idChars})
+				}
+			})
+		case "unique":
+			seen := make(map[string]bool)
+			for i := 0; i < v.Len(); i++ {
+				key := uniqueKey(v.Index(i), arg)
+				if seen[key] {
+					fail("has duplicate value %q", key)
+				}
+				seen[key] = true
+			}
+		default:
+			panic(fmt.Sprintf("handlers: unknown validate rule %q on %s", rule, path))
+		}
+	}
+
+	switch v = reflect.Indirect(v); v.Kind() {
+	case reflect.Struct:
+		validateStruct(v, path+".", errs)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				validateStruct(v.Index(i), fmt.Sprintf("%s[%d].", path, i), errs)
+			}
+		}
+	}
+}
+
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func isBlank(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// eachString calls fn for a string field, or for every element of a []string
+// field with its indexed path.
+func eachString(v reflect.Value, path string, fn func(s string, path string)) {
+	switch v.Kind() {
+	case reflect.String:
+		fn(v.String(), path)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if e := v.Index(i); e.Kind() == reflect.String {
+				fn(e.String(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+func uniqueKey(v reflect.Value, field string) string {
+	if field == "" {
+		return fmt.Sprint(v.Interface())
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == field {
+			return fmt.Sprint(v.Field(i).Interface())
+		}
+	}
+	panic(fmt.Sprintf("handlers: unique=%s refers to an unknown field of %s", field, t))
+}
+
+func isID(s string) bool {
+	for _, r := range s {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune(idChars, r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
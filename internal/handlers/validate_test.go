@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+)
+
+func decodeBody(body string, dst any) error {
+	return decodeJSON(httptest.NewRequest("POST", "/", strings.NewReader(body)), dst)
+}
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) || !errors.Is(err, models.ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	fields := make(map[string]string, len(domainErr.Fields))
+	for _, f := range domainErr.Fields {
+		fields[f.Field] = f.Message
+	}
+	return fields
+}
+
+func TestDecodeJSONReportsEveryInvalidField(t *testing.T) {
+	var req struct {
+		PRId     string `json:"pull_request_id" validate:"required,max=255,id"`
+		PRName   string `json:"pull_request_name" validate:"required,max=255"`
+		AuthorId string `json:"author_id" validate:"required,max=255,id"`
+	}
+
+	err := decodeBody(`{"pull_request_id": "pr 1", "pull_request_name": "`+strings.Repeat("a", 256)+`", "author_id": "  "}`, &req)
+
+	got := fieldErrors(t, err)
+	want := map[string]string{
+		"pull_request_id":   "must contain only letters, digits and " + idChars,
+		"pull_request_name": "must be at most 255 characters",
+		"author_id":         "is required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeJSONAcceptsWebhookIDs(t *testing.T) {
+	var req struct {
+		PRId string `json:"pull_request_id" validate:"required,max=255,id"`
+	}
+
+	for _, id := range []string{"pr-1001", "org/repo#12", "group/project!7"} {
+		if err := decodeBody(`{"pull_request_id": "`+id+`"}`, &req); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+}
+
+func TestDecodeJSONTeamMembers(t *testing.T) {
+	var req createTeamRequest
+
+	err := decodeBody(`{"team_name": "backend", "members": [
+		{"user_id": "u1", "username": "Alice", "is_active": true},
+		{"user_id": "u1", "username": "", "is_active": true}
+	]}`, &req)
+
+	got := fieldErrors(t, err)
+	want := map[string]string{
+		"members":             `has duplicate value "u1"`,
+		"members[1].username": "is required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeJSONStringSlices(t *testing.T) {
+	var req struct {
+		UserIDs []string `json:"user_ids" validate:"required,max=255,id,unique"`
+	}
+
+	got := fieldErrors(t, decodeBody(`{"user_ids": ["u1", "u 2", "u1"]}`, &req))
+	if len(got) != 2 || got["user_ids[1]"] == "" || got["user_ids"] == "" {
+		t.Fatalf("unexpected fields %v", got)
+	}
+
+	got = fieldErrors(t, decodeBody(`{"user_ids": []}`, &req))
+	if got["user_ids"] != "is required" {
+		t.Fatalf("unexpected fields %v", got)
+	}
+}
+
+type setIsActiveRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	IsActive *bool  `json:"is_active" validate:"required"`
+}
+
+func TestDecodeJSONRejectsMalformedBodies(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"unknown field", `{"user_id": "u1", "is_active": true, "admin": true}`, "admin"},
+		{"wrong type", `{"user_id": "u1", "is_active": "yes"}`, "is_active"},
+		{"missing bool", `{"user_id": "u1"}`, "is_active"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req setIsActiveRequest
+			got := fieldErrors(t, decodeBody(tt.body, &req))
+			if _, ok := got[tt.field]; !ok || len(got) != 1 {
+				t.Fatalf("expected an error for %s only, got %v", tt.field, got)
+			}
+		})
+	}
+
+	for _, body := range []string{``, `{"user_id": `, `{"user_id": "u1", "is_active": true} {}`} {
+		var req setIsActiveRequest
+		if err := decodeBody(body, &req); err != errInvalidBody {
+			t.Errorf("%q: expected invalid request body, got %v", body, err)
+		}
+	}
+}
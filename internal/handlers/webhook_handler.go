@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+const maxWebhookBodySize = 10 << 20
+
+type WebhookHandler struct {
+	service      *service.WebhookService
+	githubSecret string
+	gitlabToken  string
+}
+
+func NewWebhookHandler(svc *service.WebhookService, githubSecret string, gitlabToken string) *WebhookHandler {
+	return &WebhookHandler{service: svc, githubSecret: githubSecret, gitlabToken: gitlabToken}
+}
+
+func (h *WebhookHandler) GitHub(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, r, errInvalidBody)
+		return
+	}
+
+	if !verifyGitHubSignature(h.githubSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, r, models.ErrInvalidSignature.WithMessage("invalid webhook signature"))
+		return
+	}
+
+	event, err := parseGitHubEvent(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil {
+		writeError(w, r, errInvalidBody)
+		return
+	}
+
+	h.apply(w, r, event)
+}
+
+func (h *WebhookHandler) GitLab(w http.ResponseWriter, r *http.Request) {
+	if !verifyGitLabToken(h.gitlabToken, r.Header.Get("X-Gitlab-Token")) {
+		writeError(w, r, models.ErrInvalidSignature.WithMessage("invalid webhook token"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, r, errInvalidBody)
+		return
+	}
+
+	event, err := parseGitLabEvent(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		writeError(w, r, errInvalidBody)
+		return
+	}
+
+	h.apply(w, r, event)
+}
+
+func (h *WebhookHandler) apply(w http.ResponseWriter, r *http.Request, event *service.WebhookEvent) {
+	result := &service.WebhookResult{Status: "ignored", Reason: "unsupported event"}
+	if event != nil {
+		var err error
+		result, err = h.service.Apply(r.Context(), event)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("log output is not JSON: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestNewRejectsUnknownLevel(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept")
+	if lines := decodeLines(t, &buf); len(lines) != 1 || lines[0]["msg"] != "kept" {
+		t.Fatalf("lines = %v", lines)
+	}
+}
+
+func TestFromContextFallsBackToDefault(t *testing.T) {
+	if FromContext(t.Context()) != slog.Default() {
+		t.Fatal("expected slog.Default()")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(Middleware(logger))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).DebugContext(r.Context(), "handler")
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		path   string
+		route  string
+		status int
+		lines  int
+	}{
+		{"/users/u1", "/users/{id}", http.StatusTeapot, 2},
+		{"/panic", "/panic", http.StatusInternalServerError, 2},
+		{"/missing", "unmatched", http.StatusNotFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(middleware.RequestIDHeader, "req-42")
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			lines := decodeLines(t, &buf)
+			if len(lines) != tt.lines {
+				t.Fatalf("got %d lines, want %d: %v", len(lines), tt.lines, lines)
+			}
+			for _, line := range lines {
+				if line["request_id"] != "req-42" || line["route"] != tt.route {
+					t.Errorf("line lacks request context: %v", line)
+				}
+			}
+
+			last := lines[len(lines)-1]
+			if last["msg"] != "request" || last["status"] != float64(tt.status) {
+				t.Errorf("unexpected request line %v", last)
+			}
+			if _, ok := last["latency_ms"].(float64); !ok {
+				t.Errorf("request line has no latency: %v", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware puts a logger carrying request_id and route into the request
+// context and logs one line per request with its status and latency. It must
+// run after middleware.RequestID, and after tracing.Middleware for lines to
+// carry the trace_id. It also recovers panics: the panic and its
+// stack are logged and the client gets a 500.
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
+
+			logger := base
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				logger = slog.New(&routeHandler{Handler: base.Handler(), rctx: rctx})
+			}
+			logger = logger.With(slog.String("request_id", middleware.GetReqID(ctx)))
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				logger = logger.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+					logger.ErrorContext(ctx, "panic serving request",
+						slog.String("panic", fmt.Sprint(rec)),
+						slog.String("stack", string(debug.Stack())))
+					if ww.Status() == 0 {
+						ww.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				if status >= http.StatusInternalServerError {
+					level = slog.LevelError
+				}
+				logger.LogAttrs(ctx, level, "request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.Int("bytes", ww.BytesWritten()))
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(WithContext(ctx, logger)))
+		})
+	}
+}
+
+// routeHandler adds the chi route pattern to every record. The pattern is
+// only complete once routing has finished, so it is read when a record is
+// written rather than when the request logger is built. chi recycles its
+// route context once the request is served, so the request logger must not
+// outlive the request.
+type routeHandler struct {
+	slog.Handler
+	rctx *chi.Context
+}
+
+func (h *routeHandler) Handle(ctx context.Context, r slog.Record) error {
+	route := h.rctx.RoutePattern()
+	if route == "" {
+		route = "unmatched"
+	}
+	r = r.Clone()
+	r.AddAttrs(slog.String("route", route))
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *routeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &routeHandler{Handler: h.Handler.WithAttrs(attrs), rctx: h.rctx}
+}
+
+func (h *routeHandler) WithGroup(name string) slog.Handler {
+	return &routeHandler{Handler: h.Handler.WithGroup(name), rctx: h.rctx}
+}
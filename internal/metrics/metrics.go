@@ -0,0 +1,256 @@
+// Package metrics exposes the service metrics in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// collectTimeout bounds the database queries made while serving a scrape.
+const collectTimeout = 5 * time.Second
+
+// Metrics owns a registry with HTTP, connection pool and domain metrics. It
+// implements service.Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	prsCreated    prometheus.Counter
+	assignments   *prometheus.CounterVec
+	reassignments *prometheus.CounterVec
+	noCandidate   prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		prsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "prs_created_total",
+			Help: "Pull requests created.",
+		}),
+		assignments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "reviewer_assignments_total",
+			Help: "Reviewers assigned when a pull request is opened, by strategy.",
+		}, []string{"strategy"}),
+		reassignments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "reassignments_total",
+			Help: "Reviewers replaced by another team member, by reason.",
+		}, []string{"reason"}),
+		noCandidate: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "no_candidate_total",
+			Help: "Reviewer replacements that found no active candidate.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.prsCreated,
+		m.assignments,
+		m.reassignments,
+		m.noCandidate,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request under its chi route pattern, so
+// /webhooks/subscriptions/{id} is one series regardless of the id. Requests
+// that match no route are recorded as "unmatched".
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RegisterPool exports the connection pool statistics.
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	m.registry.MustRegister(&poolCollector{pool: pool})
+}
+
+// OpenReviewCounter is satisfied by repository.PRRepo.
+type OpenReviewCounter interface {
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+}
+
+// RegisterOpenReviews exports the number of open reviews per user. It is
+// computed on every scrape, so users without open reviews drop out.
+func (m *Metrics) RegisterOpenReviews(counter OpenReviewCounter) {
+	m.registry.MustRegister(&openReviewsCollector{counter: counter})
+}
+
+// OutboxStatsSource is satisfied by service.OutboxRelay.
+type OutboxStatsSource interface {
+	Stats(ctx context.Context) (*models.OutboxStats, error)
+}
+
+// RegisterOutbox exports the outbox queue and when the relay last finished a
+// pass, computed on every scrape. A stalled relay is meant to be caught by
+// alerting on these rather than by the readiness probe.
+func (m *Metrics) RegisterOutbox(source OutboxStatsSource) {
+	m.registry.MustRegister(&outboxCollector{source: source})
+}
+
+func (m *Metrics) PRCreated() {
+	m.prsCreated.Inc()
+}
+
+func (m *Metrics) ReviewersAssigned(strategy string, count int) {
+	m.assignments.WithLabelValues(strategy).Add(float64(count))
+}
+
+func (m *Metrics) ReviewerReassigned(reason string) {
+	m.reassignments.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) NoCandidate() {
+	m.noCandidate.Inc()
+}
+
+var openReviewsDesc = prometheus.NewDesc(
+	"open_reviews",
+	"OPEN pull requests the user is assigned to review.",
+	[]string{"user_id"}, nil,
+)
+
+type openReviewsCollector struct {
+	counter OpenReviewCounter
+}
+
+func (c *openReviewsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openReviewsDesc
+}
+
+func (c *openReviewsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountOpenReviews(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to collect open reviews", slog.Any("error", err))
+		ch <- prometheus.NewInvalidMetric(openReviewsDesc, err)
+		return
+	}
+
+	for userID, count := range counts {
+		ch <- prometheus.MustNewConstMetric(openReviewsDesc, prometheus.GaugeValue, float64(count), userID)
+	}
+}
+
+var (
+	outboxPending = prometheus.NewDesc("outbox_pending_events", "Events waiting in the outbox.", nil, nil)
+	outboxLag     = prometheus.NewDesc("outbox_lag_seconds", "Age of the oldest event waiting in the outbox.", nil, nil)
+	outboxLastRun = prometheus.NewDesc("outbox_relay_last_run_timestamp_seconds", "Unix time the outbox relay last finished a pass; absent before the first one.", nil, nil)
+)
+
+type outboxCollector struct {
+	source OutboxStatsSource
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{outboxPending, outboxLag, outboxLastRun} {
+		ch <- d
+	}
+}
+
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	stats, err := c.source.Stats(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to collect outbox stats", slog.Any("error", err))
+		ch <- prometheus.NewInvalidMetric(outboxPending, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(outboxPending, prometheus.GaugeValue, float64(stats.Pending))
+	ch <- prometheus.MustNewConstMetric(outboxLag, prometheus.GaugeValue, stats.LagSeconds)
+	if stats.LastRunAt != nil {
+		ch <- prometheus.MustNewConstMetric(outboxLastRun, prometheus.GaugeValue, float64(stats.LastRunAt.UnixMilli())/1000)
+	}
+}
+
+var (
+	poolAcquiredConns = prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently in use.", nil, nil)
+	poolIdleConns     = prometheus.NewDesc("pgxpool_idle_conns", "Idle connections.", nil, nil)
+	poolTotalConns    = prometheus.NewDesc("pgxpool_total_conns", "Open connections.", nil, nil)
+	poolMaxConns      = prometheus.NewDesc("pgxpool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquires      = prometheus.NewDesc("pgxpool_acquire_total", "Successful connection acquisitions.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc("pgxpool_empty_acquire_total", "Acquisitions that had to wait for a connection.", nil, nil)
+	poolCanceled      = prometheus.NewDesc("pgxpool_canceled_acquire_total", "Acquisitions canceled by their context.", nil, nil)
+	poolAcquireTime   = prometheus.NewDesc("pgxpool_acquire_duration_seconds_total", "Time spent acquiring connections.", nil, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns, poolAcquires, poolEmptyAcquires, poolCanceled, poolAcquireTime} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(poolAcquiredConns, float64(stat.AcquiredConns()))
+	gauge(poolIdleConns, float64(stat.IdleConns()))
+	gauge(poolTotalConns, float64(stat.TotalConns()))
+	gauge(poolMaxConns, float64(stat.MaxConns()))
+	counter(poolAcquires, float64(stat.AcquireCount()))
+	counter(poolEmptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(poolCanceled, float64(stat.CanceledAcquireCount()))
+	counter(poolAcquireTime, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareLabelsRequestsByRoutePattern(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		route  string
+		status string
+		want   float64
+	}{
+		{"/items/{id}", "200", 2},
+		{"/items/{id}", "404", 1},
+		{"unmatched", "404", 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", tt.route, tt.status))
+		if got != tt.want {
+			t.Errorf("%s %s: %v requests, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+
+	if n := testutil.CollectAndCount(m.httpDuration); n != 2 {
+		t.Errorf("expected latency series for two routes, got %d", n)
+	}
+}
+
+func TestDomainMetrics(t *testing.T) {
+	m := New()
+	m.PRCreated()
+	m.ReviewersAssigned("round_robin", 2)
+	m.ReviewersAssigned("round_robin", 0)
+	m.ReviewerReassigned("manual")
+	m.NoCandidate()
+
+	if got := testutil.ToFloat64(m.prsCreated); got != 1 {
+		t.Errorf("prs_created_total = %v", got)
+	}
+	if got := testutil.ToFloat64(m.assignments.WithLabelValues("round_robin")); got != 2 {
+		t.Errorf("reviewer_assignments_total = %v", got)
+	}
+	if got := testutil.ToFloat64(m.reassignments.WithLabelValues("manual")); got != 1 {
+		t.Errorf("reassignments_total = %v", got)
+	}
+	if got := testutil.ToFloat64(m.noCandidate); got != 1 {
+		t.Errorf("no_candidate_total = %v", got)
+	}
+}
+
+type fakeCounter struct {
+	counts map[string]int
+	err    error
+}
+
+func (f *fakeCounter) CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return f.counts, f.err
+}
+
+func TestOpenReviewsGauge(t *testing.T) {
+	m := New()
+	counter := &fakeCounter{counts: map[string]int{"u1": 2, "u2": 1}}
+	m.RegisterOpenReviews(counter)
+
+	want := `
+# HELP open_reviews OPEN pull requests the user is assigned to review.
+# TYPE open_reviews gauge
+open_reviews{user_id="u1"} 2
+open_reviews{user_id="u2"} 1
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(want), "open_reviews"); err != nil {
+		t.Fatal(err)
+	}
+
+	counter.err = errors.New("connection refused")
+	if _, err := m.registry.Gather(); err == nil {
+		t.Fatal("expected a gather error when counting fails")
+	}
+}
+
+type fakeOutbox struct {
+	stats *models.OutboxStats
+}
+
+func (f *fakeOutbox) Stats(ctx context.Context) (*models.OutboxStats, error) {
+	return f.stats, nil
+}
+
+func TestOutboxGauges(t *testing.T) {
+	m := New()
+	source := &fakeOutbox{stats: &models.OutboxStats{Pending: 3, LagSeconds: 12.5}}
+	m.RegisterOutbox(source)
+
+	want := `
+# HELP outbox_lag_seconds Age of the oldest event waiting in the outbox.
+# TYPE outbox_lag_seconds gauge
+outbox_lag_seconds 12.5
+# HELP outbox_pending_events Events waiting in the outbox.
+# TYPE outbox_pending_events gauge
+outbox_pending_events 3
+`
+	names := []string{"outbox_pending_events", "outbox_lag_seconds", "outbox_relay_last_run_timestamp_seconds"}
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(want), names...); err != nil {
+		t.Fatal(err)
+	}
+
+	lastRun := time.Unix(1790000000, 0)
+	source.stats.LastRunAt = &lastRun
+	want += `# HELP outbox_relay_last_run_timestamp_seconds Unix time the outbox relay last finished a pass; absent before the first one.
+# TYPE outbox_relay_last_run_timestamp_seconds gauge
+outbox_relay_last_run_timestamp_seconds 1.79e+09
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(want), names...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,403 @@
+// Package migrations applies the versioned SQL files embedded from sql/.
+//
+// Every migration is a pair of files NNNN_name.up.sql and NNNN_name.down.sql.
+// Applied versions are tracked in the schema_migrations table; a Postgres
+// advisory lock makes sure that only one replica migrates at a time.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey is the pg_advisory_lock key shared by all replicas of the service.
+const lockKey int64 = 0x70725f7265766965
+
+var ErrUnknownVersion = errors.New("database schema is newer than this binary")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := load(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// Latest returns the highest version known to this binary.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		current := currentVersion(applied)
+		if current == 0 {
+			return nil
+		}
+
+		target := int64(0)
+		for _, mig := range m.migrations {
+			if mig.Version < current {
+				target = mig.Version
+			}
+		}
+
+		return m.migrate(ctx, conn, applied, target)
+	})
+}
+
+// To migrates up or down until target is the latest applied version.
+func (m *Migrator) To(ctx context.Context, target int64) error {
+	if target != 0 && m.find(target) == nil {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, applied, target)
+	})
+}
+
+// Status lists every known migration and whether it has been applied. It only
+// reads: no advisory lock is taken and a database without schema_migrations
+// reports every migration as pending.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]time.Time{}
+	if exists {
+		applied, err = m.applied(ctx, m.pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// CurrentVersion returns the highest applied version, 0 for an empty database
+// including one where schema_migrations has not been created yet.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int64, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int64
+	err = m.pool.QueryRow(ctx, `
+		SELECT COALESCE(MAX(version), 0) FROM schema_migrations
+	`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// tableExists reports whether schema_migrations has been created, without
+// creating it.
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := m.pool.QueryRow(ctx, `
+		SELECT to_regclass('schema_migrations') IS NOT NULL
+	`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return exists, nil
+}
+
+// Verify returns the applied version and an error unless it is exactly the
+// latest version known to this binary.
+func (m *Migrator) Verify(ctx context.Context) (int64, error) {
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch latest := m.Latest(); {
+	case current > latest:
+		return current, fmt.Errorf("%w: version %d, latest known %d", ErrUnknownVersion, current, latest)
+	case current < latest:
+		return current, fmt.Errorf("database schema is at version %d, %d is pending", current, latest)
+	}
+	return current, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// querier is satisfied by both the pool and a locked connection.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// applied returns the applied versions and fails if any of them is unknown
+// to this binary, so an old build never runs against a newer schema.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	rows, err := q.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for version := range applied {
+		if m.find(version) == nil {
+			return nil, fmt.Errorf("%w: version %d is applied, latest known is %d", ErrUnknownVersion, version, m.Latest())
+		}
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, applied map[int64]time.Time, target int64) error {
+	for _, mig := range m.migrations {
+		if mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig, true); err != nil {
+			return err
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= target {
+			break
+		}
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	if !up {
+		direction, script = "down", mig.Down
+	}
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+
+		if up {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO schema_migrations (version, name) VALUES ($1, $2)
+			`, mig.Version, mig.Name)
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func currentVersion(applied map[int64]time.Time) int64 {
+	var current int64
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
+
+// load reads NNNN_name.{up,down}.sql pairs from dir and returns them sorted
+// by version. Every migration must have both directions.
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		var up bool
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			up, base = true, strings.TrimSuffix(base, ".up")
+		case strings.HasSuffix(base, ".down"):
+			base = strings.TrimSuffix(base, ".down")
+		default:
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", name)
+		}
+
+		versionPart, migName, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s must be named NNNN_name", name)
+		}
+
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has invalid version", name)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, exists := byVersion[version]
+		if !exists {
+			mig = &Migration{Version: version, Name: migName}
+			byVersion[version] = mig
+		}
+		if mig.Name != migName {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, mig.Name, migName)
+		}
+
+		if up {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsAreSequential(t *testing.T) {
+	migrations, err := load(files, "sql")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	for i, mig := range migrations {
+		if mig.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want %d", i, mig.Version, i+1)
+		}
+		if mig.Up == "" || mig.Down == "" {
+			t.Errorf("migration %04d_%s is missing a direction", mig.Version, mig.Name)
+		}
+	}
+}
+
+func TestLoadRejectsMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0001_init.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"sql/0001_init.down.sql": {Data: []byte("DROP TABLE a;")},
+		"sql/0002_more.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+	}
+
+	if _, err := load(fsys, "sql"); err == nil {
+		t.Fatal("expected an error for a migration without down file")
+	}
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0010_later.up.sql":   {Data: []byte("SELECT 10;")},
+		"sql/0010_later.down.sql": {Data: []byte("SELECT -10;")},
+		"sql/0002_early.up.sql":   {Data: []byte("SELECT 2;")},
+		"sql/0002_early.down.sql": {Data: []byte("SELECT -2;")},
+	}
+
+	migrations, err := load(fsys, "sql")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Fatalf("unexpected order: %+v", migrations)
+	}
+
+	if migrations[1].Name != "later" || migrations[1].Down != "SELECT -10;" {
+		t.Fatalf("unexpected migration: %+v", migrations[1])
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for _, name := range []string{"sql/init.up.sql", "sql/0001_init.sql", "sql/abc_init.up.sql"} {
+		fsys := fstest.MapFS{name: {Data: []byte("SELECT 1;")}}
+		if _, err := load(fsys, "sql"); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS allow_fewer_than_required;
+ALTER TABLE teams DROP COLUMN IF EXISTS min_reviewers;
+ALTER TABLE teams DROP COLUMN IF EXISTS reviewers_per_pr;
+ALTER TABLE teams DROP COLUMN IF EXISTS reviewer_strategy;
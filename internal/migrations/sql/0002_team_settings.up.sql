@@ -0,0 +1,4 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS reviewer_strategy VARCHAR(32) NULL;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS reviewers_per_pr INTEGER NOT NULL DEFAULT 2;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS min_reviewers INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS allow_fewer_than_required BOOLEAN NOT NULL DEFAULT true;
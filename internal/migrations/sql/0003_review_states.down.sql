@@ -0,0 +1,3 @@
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS decided_at;
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS assigned_at;
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS review_state;
@@ -0,0 +1,3 @@
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS review_state VARCHAR(20) NOT NULL DEFAULT 'PENDING';
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP NULL;
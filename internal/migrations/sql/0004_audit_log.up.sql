@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS pr_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id),
+    action VARCHAR(50) NOT NULL,
+    actor VARCHAR(255) NULL,
+    forced BOOLEAN NOT NULL DEFAULT false,
+    details TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_pr ON pr_audit_log(pull_request_id);
@@ -0,0 +1,5 @@
+-- Lossy: the OPEN/MERGED status set has no place for drafts or closed PRs,
+-- so both come back as OPEN and closed_at is dropped. Which PRs were DRAFT
+-- or CLOSED cannot be recovered by migrating up again.
+UPDATE pull_requests SET status = 'OPEN' WHERE status IN ('DRAFT', 'CLOSED');
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS closed_at;
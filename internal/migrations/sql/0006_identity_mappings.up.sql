@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS identity_mappings (
+    provider VARCHAR(32) NOT NULL,
+    external_login VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id),
+    PRIMARY KEY (provider, external_login)
+);
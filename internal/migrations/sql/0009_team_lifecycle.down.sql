@@ -0,0 +1,7 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name);
+-- Fails while users removed from their team exist: put them into a team first.
+ALTER TABLE users ALTER COLUMN team_name SET NOT NULL;
+
+ALTER TABLE teams DROP COLUMN IF EXISTS archived_at;
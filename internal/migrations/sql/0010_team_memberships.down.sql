@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team_name VARCHAR(255) NULL;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE;
+
+-- Users in several teams keep only the team they joined first.
+UPDATE users u SET team_name = (
+    SELECT m.team_name FROM team_memberships m
+    WHERE m.user_id = u.user_id
+    ORDER BY m.joined_at, m.team_name
+    LIMIT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_name);
+
+DROP INDEX IF EXISTS idx_pr_team;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS team_name;
+
+DROP TABLE IF EXISTS team_memberships;
@@ -0,0 +1,30 @@
+-- joined_at uses clock_timestamp() so that memberships created in one
+-- transaction still have a defined order: a user's first team is the
+-- default team of their PRs.
+CREATE TABLE IF NOT EXISTS team_memberships (
+    team_name VARCHAR(255) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id),
+    role VARCHAR(32) NOT NULL DEFAULT 'member',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    joined_at TIMESTAMP NOT NULL DEFAULT clock_timestamp(),
+    PRIMARY KEY (team_name, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_memberships_user ON team_memberships(user_id);
+
+-- users.is_active stays as the global flag, so existing memberships start active.
+INSERT INTO team_memberships (team_name, user_id, joined_at)
+SELECT team_name, user_id, COALESCE(created_at, CURRENT_TIMESTAMP) FROM users WHERE team_name IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS team_name VARCHAR(255) NULL
+    REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE pull_requests pr SET team_name = u.team_name
+FROM users u
+WHERE u.user_id = pr.author_id AND pr.team_name IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_pr_team ON pull_requests(team_name);
+
+DROP INDEX IF EXISTS idx_users_team;
+ALTER TABLE users DROP COLUMN IF EXISTS team_name;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS webhook_retries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id VARCHAR(64) NOT NULL REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    attempt INTEGER NOT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_retries_due ON webhook_retries(next_attempt_at);
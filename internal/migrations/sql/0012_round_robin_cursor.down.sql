@@ -0,0 +1 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS round_robin_last;
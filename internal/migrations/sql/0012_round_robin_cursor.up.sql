@@ -0,0 +1 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS round_robin_last VARCHAR(255) NULL;
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Error is a domain error that carries the API error code and the HTTP status
+// it is reported with. Sentinels below are compared with errors.Is; errors
+// derived from a sentinel with WithMessage still match it.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Fields  []FieldError
+	base    *Error
+}
+
+// FieldError describes one invalid field of a request. Field is the JSON path
+// of the value, e.g. "members[1].user_id".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is e itself or the sentinel e was derived from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	for b := e; b != nil; b = b.base {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WithMessage returns an error with the same code and status as e and a more
+// specific message. The result matches e in errors.Is.
+func (e *Error) WithMessage(message string) *Error {
+	return &Error{Code: e.Code, Status: e.Status, Message: message, base: e}
+}
+
+// WithFields is WithMessage for validation failures: the message lists every
+// invalid field and the fields are reported to the client one by one.
+func (e *Error) WithFields(fields []FieldError) *Error {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	err := e.WithMessage(strings.Join(parts, "; "))
+	err.Fields = fields
+	return err
+}
+
+func newError(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+var (
+	ErrTeamExists         = newError(ErrorTeamExists, http.StatusBadRequest, "team already exists")
+	ErrPRExists           = newError(ErrorPRExists, http.StatusConflict, "PR already exists")
+	ErrPRMerged           = newError(ErrorPRMerged, http.StatusConflict, "PR is merged")
+	ErrNotAssigned        = newError(ErrorNotAssigned, http.StatusConflict, "reviewer is not assigned")
+	ErrNoCandidate        = newError(ErrorNoCandidate, http.StatusConflict, "no active replacement candidate in team")
+	ErrNotFound           = newError(ErrorNotFound, http.StatusNotFound, "resource not found")
+	ErrNotEnoughReviewers = newError(ErrorNotEnoughReviewers, http.StatusConflict, "not enough reviewers")
+	ErrNotApproved        = newError(ErrorNotApproved, http.StatusConflict, "PR is not approved")
+	ErrPRNotOpen          = newError(ErrorPRNotOpen, http.StatusConflict, "PR is not open")
+	ErrInvalidTransition  = newError(ErrorInvalidTransition, http.StatusConflict, "invalid status transition")
+	ErrInvalidSignature   = newError(ErrorInvalidSignature, http.StatusUnauthorized, "invalid signature")
+	ErrForbidden          = newError(ErrorForbidden, http.StatusForbidden, "forbidden")
+	ErrValidation         = newError(ErrorValidation, http.StatusBadRequest, "validation failed")
+	ErrInternal           = newError(ErrorInternal, http.StatusInternalServerError, "internal server error")
+	ErrTeamArchived       = newError(ErrorTeamArchived, http.StatusConflict, "team is archived")
+	ErrTeamNotEmpty       = newError(ErrorTeamNotEmpty, http.StatusConflict, "team still has members")
+	ErrMemberExists       = newError(ErrorMemberExists, http.StatusConflict, "user is already a member of the team")
+)
+
+var (
+	ErrTeamNotFound         = ErrNotFound.WithMessage("team not found")
+	ErrUserNotFound         = ErrNotFound.WithMessage("user not found")
+	ErrPRNotFound           = ErrNotFound.WithMessage("PR not found")
+	ErrIdentityNotFound     = ErrNotFound.WithMessage("identity not found")
+	ErrSubscriptionNotFound = ErrNotFound.WithMessage("subscription not found")
+	ErrMembershipNotFound   = ErrNotFound.WithMessage("user is not a member of the team")
+)
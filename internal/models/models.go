@@ -1,33 +1,84 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type TeamMember struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	IsActive bool   `json:"is_active"`
+	Role     string `json:"role,omitempty"`
 }
 
 type Team struct {
-	TeamName string        `json:"team_name"`
-	Members  []TeamMember  `json:"members"`
+	TeamName         string       `json:"team_name"`
+	Members          []TeamMember `json:"members"`
+	ReviewerStrategy string       `json:"reviewer_strategy,omitempty"`
+	Archived         bool         `json:"archived,omitempty"`
 }
 
+type TeamSettings struct {
+	TeamName               string `json:"team_name"`
+	ReviewerStrategy       string `json:"reviewer_strategy,omitempty"`
+	ReviewersPerPR         int    `json:"reviewers_per_pr"`
+	MinReviewers           int    `json:"min_reviewers"`
+	AllowFewerThanRequired bool   `json:"allow_fewer_than_required"`
+	// Archived teams get no new reviewer assignments. It is changed by
+	// archiving the team, never by UpdateSettings.
+	Archived bool `json:"archived"`
+}
+
+// TeamSettingsUpdate is a partial update of TeamSettings; nil fields keep
+// their current value.
+type TeamSettingsUpdate struct {
+	ReviewerStrategy       *string
+	ReviewersPerPR         *int
+	MinReviewers           *int
+	AllowFewerThanRequired *bool
+}
+
+// User.TeamName is the team the user joined first, which PRs are created in
+// unless another team is given. It is empty for users who are not a member
+// of any team: they are kept because their PRs and reviews reference them.
+// IsActive is global; every team membership has its own flag on top of it.
 type User struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
-	TeamName string `json:"team_name"`
+	TeamName string `json:"team_name,omitempty"`
 	IsActive bool   `json:"is_active"`
 }
 
+// PullRequest.TeamName is the team reviewers are drawn from. It is empty
+// for PRs whose team was deleted.
 type PullRequest struct {
-	ID                string    `json:"pull_request_id"`
-	Name              string    `json:"pull_request_name"`
-	AuthorID          string    `json:"author_id"`
-	Status            string    `json:"status"`
-	AssignedReviewers []string  `json:"assigned_reviewers"`
+	ID                string     `json:"pull_request_id"`
+	Name              string     `json:"pull_request_name"`
+	AuthorID          string     `json:"author_id"`
+	TeamName          string     `json:"team_name,omitempty"`
+	Status            string     `json:"status"`
+	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Reviews           []Review   `json:"reviews,omitempty"`
 	CreatedAt         *time.Time `json:"createdAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty"`
+}
+
+type Review struct {
+	ReviewerID string     `json:"reviewer_id"`
+	State      string     `json:"state"`
+	AssignedAt *time.Time `json:"assignedAt,omitempty"`
+	DecidedAt  *time.Time `json:"decidedAt,omitempty"`
+}
+
+// TeamMembership links a user to one of their teams. A user who is inactive
+// in one team can still be assigned reviews in another.
+type TeamMembership struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+	Role     string `json:"role"`
+	IsActive bool   `json:"is_active"`
 }
 
 type PullRequestShort struct {
@@ -37,18 +88,210 @@ type PullRequestShort struct {
 	Status   string `json:"status"`
 }
 
+type IdentityMapping struct {
+	Provider string `json:"provider"`
+	Login    string `json:"login"`
+	UserID   string `json:"user_id"`
+}
+
+type AuditEntry struct {
+	PullRequestID string
+	Action        string
+	Actor         string
+	Forced        bool
+	Details       string
+}
+
+type ReviewerReplacement struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldReviewerID string `json:"old_reviewer_id"`
+	NewReviewerID string `json:"new_reviewer_id,omitempty"`
+	LeftShort     bool   `json:"left_short"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+type BulkDeactivationResult struct {
+	TeamName         string                `json:"team_name"`
+	DeactivatedUsers []string              `json:"deactivated_users"`
+	Replacements     []ReviewerReplacement `json:"replacements"`
+}
+
+type MemberRemovalResult struct {
+	TeamName     string                `json:"team_name"`
+	RemovedUsers []string              `json:"removed_users"`
+	Replacements []ReviewerReplacement `json:"replacements"`
+}
+
+// TeamArchiveResult lists the open reviews of an archived team's members that
+// moved to the reassign_to team and the ones that kept their reviewer because
+// no replacement was available.
+type TeamArchiveResult struct {
+	TeamName     string                `json:"team_name"`
+	ReassignTo   string                `json:"reassign_to,omitempty"`
+	Replacements []ReviewerReplacement `json:"replacements"`
+	Flagged      []ReviewerAssignment  `json:"flagged"`
+}
+
+// TeamSyncResult is the difference between a team and the roster it was
+// synced to. With DryRun nothing was changed: the result shows what a real
+// sync would do, including the reviewer replacements.
+type TeamSyncResult struct {
+	TeamName     string                `json:"team_name"`
+	DryRun       bool                  `json:"dry_run"`
+	Created      bool                  `json:"created"`
+	Added        []TeamMember          `json:"added"`
+	Removed      []string              `json:"removed"`
+	Renamed      []MemberRename        `json:"renamed"`
+	Activated    []string              `json:"activated"`
+	Deactivated  []string              `json:"deactivated"`
+	RoleChanged  []string              `json:"role_changed"`
+	Replacements []ReviewerReplacement `json:"replacements"`
+}
+
+type MemberRename struct {
+	UserID      string `json:"user_id"`
+	OldUsername string `json:"old_username"`
+	NewUsername string `json:"new_username"`
+}
+
+type ReviewerAssignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReviewerID    string `json:"reviewer_id"`
+}
+
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type OutboxStats struct {
+	Pending    int        `json:"pending"`
+	LagSeconds float64    `json:"lag_seconds"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+}
+
+type WebhookSubscription struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url"`
+	Secret    string     `json:"secret,omitempty"`
+	Events    []string   `json:"events"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+type WebhookDelivery struct {
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	Attempt        int
+	StatusCode     int
+	Error          string
+	Delivered      bool
+}
+
+// WebhookRetry is a failed delivery of Event to Subscription that gets
+// another attempt, number Attempt, at NextAttemptAt.
+type WebhookRetry struct {
+	ID            int64
+	Subscription  WebhookSubscription
+	Event         Event
+	Attempt       int
+	NextAttemptAt time.Time
+}
+
+// ReadinessResponse is returned by GET /health/ready with one entry per
+// checked component.
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
 type ErrorResponse struct {
 	Error struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
+		Code    string       `json:"code"`
+		Message string       `json:"message"`
+		Fields  []FieldError `json:"fields,omitempty"`
 	} `json:"error"`
 }
 
 const (
-	ErrorTeamExists   = "TEAM_EXISTS"
-	ErrorPRExists     = "PR_EXISTS"
-	ErrorPRMerged     = "PR_MERGED"
-	ErrorNotAssigned  = "NOT_ASSIGNED"
-	ErrorNoCandidate  = "NO_CANDIDATE"
-	ErrorNotFound     = "NOT_FOUND"
-)
\ No newline at end of file
+	ErrorTeamExists  = "TEAM_EXISTS"
+	ErrorPRExists    = "PR_EXISTS"
+	ErrorPRMerged    = "PR_MERGED"
+	ErrorNotAssigned = "NOT_ASSIGNED"
+	ErrorNoCandidate = "NO_CANDIDATE"
+	ErrorNotFound    = "NOT_FOUND"
+
+	ErrorNotEnoughReviewers = "NOT_ENOUGH_REVIEWERS"
+	ErrorNotApproved        = "NOT_APPROVED"
+	ErrorPRNotOpen          = "PR_NOT_OPEN"
+	ErrorInvalidTransition  = "INVALID_TRANSITION"
+	ErrorInvalidSignature   = "INVALID_SIGNATURE"
+	ErrorForbidden          = "FORBIDDEN"
+	ErrorValidation         = "VALIDATION_ERROR"
+	ErrorInternal           = "INTERNAL"
+	ErrorTeamArchived       = "TEAM_ARCHIVED"
+	ErrorTeamNotEmpty       = "TEAM_NOT_EMPTY"
+	ErrorMemberExists       = "MEMBER_EXISTS"
+)
+
+const (
+	PRStatusDraft  = "DRAFT"
+	PRStatusOpen   = "OPEN"
+	PRStatusClosed = "CLOSED"
+	PRStatusMerged = "MERGED"
+)
+
+const (
+	StrategyRandom      = "random"
+	StrategyRoundRobin  = "round_robin"
+	StrategyLeastLoaded = "least_loaded"
+	StrategyWeighted    = "weighted"
+)
+
+const (
+	ReviewPending          = "PENDING"
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewCommented        = "COMMENTED"
+)
+
+const (
+	RoleMember = "member"
+	RoleLead   = "lead"
+)
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+const (
+	EventPRCreated          = "pr.created"
+	EventReviewerAssigned   = "reviewer.assigned"
+	EventReviewerReassigned = "reviewer.reassigned"
+	EventPRMerged           = "pr.merged"
+	EventUserDeactivated    = "user.deactivated"
+)
+
+const (
+	HealthReady    = "ready"
+	HealthNotReady = "not_ready"
+	HealthUp       = "up"
+	HealthDown     = "down"
+)
+
+const (
+	ReassignReasonManual      = "manual"
+	ReassignReasonDeactivated = "user_deactivated"
+	ReassignReasonRemoved     = "member_removed"
+	ReassignReasonArchived    = "team_archived"
+)
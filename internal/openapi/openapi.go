@@ -0,0 +1,18 @@
+// Package openapi embeds the OpenAPI description of the HTTP API and the
+// Swagger UI page that renders it.
+package openapi
+
+import _ "embed"
+
+// Spec is the OpenAPI 3.1 document for every route registered in cmd/router.go.
+// cmd/router_test.go replays requests against the router and checks the
+// responses against it, so a route or field change must be reflected here.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// SwaggerUI is an HTML page that loads Swagger UI from a CDN and points it at
+// /openapi.json.
+//
+//go:embed swagger.html
+var SwaggerUI []byte
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepository struct {
+	db DBTX
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: pool}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO pr_audit_log (pull_request_id, action, actor, forced, details) VALUES ($1, $2, $3, $4, $5)",
+		entry.PullRequestID, entry.Action, entry.Actor, entry.Forced, entry.Details)
+	if isForeignKeyViolation(err) {
+		return models.ErrPRNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry for PR %s: %w", entry.PullRequestID, err)
+	}
+	return nil
+}
@@ -0,0 +1,907 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+)
+
+// backend is one implementation of the repository interfaces under test.
+// newBackend must return an empty store for every call.
+type backend struct {
+	repos *Repos
+	tx    Transactor
+}
+
+// runConformance checks that a backend behaves like every other one. It is
+// run against the in-memory store and, when a database is available, against
+// PostgreSQL.
+func runConformance(t *testing.T, newBackend func(t *testing.T) backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b backend)
+	}{
+		{"Teams", testTeams},
+		{"TeamLifecycle", testTeamLifecycle},
+		{"Users", testUsers},
+		{"Memberships", testMemberships},
+		{"PullRequests", testPullRequests},
+		{"ReviewStates", testReviewStates},
+		{"PRStatus", testPRStatus},
+		{"Audit", testAudit},
+		{"Identities", testIdentities},
+		{"Subscriptions", testSubscriptions},
+		{"WebhookRetries", testWebhookRetries},
+		{"Outbox", testOutbox},
+		{"Transactions", testTransactions},
+		{"RowLock", testRowLock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBackend(t))
+		})
+	}
+}
+
+// wantError fails unless err matches target. A nil target accepts any error.
+func wantError(t *testing.T, err error, target error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error %v, got nil", target)
+	}
+	if target != nil && !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// seedTeam creates teamName with the given members, all active.
+func seedTeam(t *testing.T, b backend, teamName string, userIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	must(t, b.repos.Team.CreateTeam(ctx, &models.Team{TeamName: teamName}))
+	for _, id := range userIDs {
+		must(t, b.repos.User.CreateOrUpdateUser(ctx, &models.User{
+			UserID:   id,
+			Username: "name-" + id,
+			IsActive: true,
+		}))
+		must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{
+			TeamName: teamName,
+			UserID:   id,
+			Role:     models.RoleMember,
+			IsActive: true,
+		}))
+	}
+}
+
+func seedPR(t *testing.T, b backend, prID string, authorID string, reviewerIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	must(t, b.repos.PR.CreatePR(ctx, &models.PullRequest{
+		ID:       prID,
+		Name:     "name-" + prID,
+		AuthorID: authorID,
+		Status:   models.PRStatusOpen,
+	}))
+	if len(reviewerIDs) > 0 {
+		must(t, b.repos.PR.AssignReviewers(ctx, prID, reviewerIDs))
+	}
+}
+
+func testTeams(t *testing.T, b backend) {
+	ctx := context.Background()
+
+	_, err := b.repos.Team.GetTeam(ctx, "backend")
+	wantError(t, err, models.ErrTeamNotFound)
+	_, err = b.repos.Team.GetSettings(ctx, "backend")
+	wantError(t, err, models.ErrTeamNotFound)
+	wantError(t, b.repos.Team.UpdateSettings(ctx, &models.TeamSettings{TeamName: "backend", ReviewersPerPR: 1}), models.ErrTeamNotFound)
+
+	must(t, b.repos.Team.CreateTeam(ctx, &models.Team{TeamName: "backend", ReviewerStrategy: models.StrategyRoundRobin}))
+	wantError(t, b.repos.Team.CreateTeam(ctx, &models.Team{TeamName: "backend"}), models.ErrTeamExists)
+
+	settings, err := b.repos.Team.GetSettings(ctx, "backend")
+	must(t, err)
+	want := models.TeamSettings{
+		TeamName:               "backend",
+		ReviewerStrategy:       models.StrategyRoundRobin,
+		ReviewersPerPR:         2,
+		MinReviewers:           1,
+		AllowFewerThanRequired: true,
+	}
+	if *settings != want {
+		t.Fatalf("default settings = %+v, want %+v", *settings, want)
+	}
+
+	updated := models.TeamSettings{TeamName: "backend", ReviewersPerPR: 3, MinReviewers: 2}
+	must(t, b.repos.Team.UpdateSettings(ctx, &updated))
+	settings, err = b.repos.Team.GetSettings(ctx, "backend")
+	must(t, err)
+	if *settings != updated {
+		t.Fatalf("updated settings = %+v, want %+v", *settings, updated)
+	}
+
+	_, err = b.repos.Team.GetRoundRobinCursorForUpdate(ctx, "frontend")
+	wantError(t, err, models.ErrTeamNotFound)
+	wantError(t, b.repos.Team.SetRoundRobinCursor(ctx, "frontend", "u1"), models.ErrTeamNotFound)
+	cursor, err := b.repos.Team.GetRoundRobinCursorForUpdate(ctx, "backend")
+	if err != nil || cursor != "" {
+		t.Fatalf("initial round robin cursor = %q (%v), want empty", cursor, err)
+	}
+	must(t, b.repos.Team.SetRoundRobinCursor(ctx, "backend", "u2"))
+	cursor, err = b.repos.Team.GetRoundRobinCursorForUpdate(ctx, "backend")
+	if err != nil || cursor != "u2" {
+		t.Fatalf("round robin cursor = %q (%v), want u2", cursor, err)
+	}
+
+	must(t, b.repos.User.CreateOrUpdateUser(ctx, &models.User{UserID: "u2", Username: "Bob", IsActive: true}))
+	must(t, b.repos.User.CreateOrUpdateUser(ctx, &models.User{UserID: "u1", Username: "Alice", IsActive: true}))
+	must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "backend", UserID: "u2", Role: models.RoleMember, IsActive: false}))
+	must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "backend", UserID: "u1", Role: models.RoleLead, IsActive: true}))
+
+	team, err := b.repos.Team.GetTeam(ctx, "backend")
+	must(t, err)
+	wantMembers := []models.TeamMember{
+		{UserID: "u1", Username: "Alice", IsActive: true, Role: models.RoleLead},
+		{UserID: "u2", Username: "Bob", IsActive: false, Role: models.RoleMember},
+	}
+	if team.TeamName != "backend" || !reflect.DeepEqual(team.Members, wantMembers) {
+		t.Fatalf("team = %+v, want members %+v", team, wantMembers)
+	}
+}
+
+func testTeamLifecycle(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2")
+	seedTeam(t, b, "frontend", "u3")
+	must(t, b.repos.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "name-pr-1", AuthorID: "u1", TeamName: "backend", Status: models.PRStatusOpen}))
+	must(t, b.repos.PR.AssignReviewers(ctx, "pr-1", []string{"u2"}))
+	must(t, b.repos.Team.SetRoundRobinCursor(ctx, "backend", "u2"))
+
+	wantError(t, b.repos.Team.RenameTeam(ctx, "missing", "other"), models.ErrTeamNotFound)
+	wantError(t, b.repos.Team.RenameTeam(ctx, "backend", "frontend"), models.ErrTeamExists)
+	must(t, b.repos.Team.RenameTeam(ctx, "backend", "platform"))
+
+	_, err := b.repos.Team.GetSettings(ctx, "backend")
+	wantError(t, err, models.ErrTeamNotFound)
+	members, err := b.repos.User.GetTeamMembers(ctx, "platform")
+	must(t, err)
+	if got := ids(members); !reflect.DeepEqual(got, []string{"u1", "u2"}) || members[0].TeamName != "platform" {
+		t.Fatalf("members after rename = %+v", members)
+	}
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.TeamName != "platform" {
+		t.Fatalf("PR team after rename = %q, want platform", pr.TeamName)
+	}
+	if cursor, err := b.repos.Team.GetRoundRobinCursorForUpdate(ctx, "platform"); err != nil || cursor != "u2" {
+		t.Fatalf("round robin cursor after rename = %q (%v), want u2", cursor, err)
+	}
+
+	wantError(t, b.repos.Team.ArchiveTeam(ctx, "missing"), models.ErrTeamNotFound)
+	must(t, b.repos.Team.ArchiveTeam(ctx, "platform"))
+	must(t, b.repos.Team.ArchiveTeam(ctx, "platform"))
+	must(t, b.repos.Team.UpdateSettings(ctx, &models.TeamSettings{TeamName: "platform", ReviewersPerPR: 1}))
+	settings, err := b.repos.Team.GetSettings(ctx, "platform")
+	must(t, err)
+	if !settings.Archived {
+		t.Fatal("UpdateSettings must not unarchive the team")
+	}
+	team, err := b.repos.Team.GetTeam(ctx, "platform")
+	must(t, err)
+	if !team.Archived {
+		t.Fatalf("team = %+v, want archived", team)
+	}
+
+	wantError(t, b.repos.Team.DeleteTeam(ctx, "missing"), models.ErrTeamNotFound)
+	wantError(t, b.repos.Team.DeleteTeam(ctx, "platform"), models.ErrTeamNotEmpty)
+
+	for _, id := range []string{"u1", "u2"} {
+		must(t, b.repos.Membership.DeleteMembership(ctx, "platform", id))
+	}
+
+	// Removed users keep their PRs and reviews.
+	user, err := b.repos.User.GetUser(ctx, "u2")
+	must(t, err)
+	if *user != (models.User{UserID: "u2", Username: "name-u2", IsActive: true}) {
+		t.Fatalf("removed user = %+v", *user)
+	}
+	pr, err = b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.AuthorID != "u1" || !reflect.DeepEqual(pr.AssignedReviewers, []string{"u2"}) {
+		t.Fatalf("PR of removed users = %+v", pr)
+	}
+	members, err = b.repos.User.GetTeamMembers(ctx, "")
+	must(t, err)
+	if len(members) != 0 {
+		t.Fatalf("users without a team must not form a team, got %v", ids(members))
+	}
+
+	// The PR outlives its team.
+	must(t, b.repos.Team.DeleteTeam(ctx, "platform"))
+	_, err = b.repos.Team.GetTeam(ctx, "platform")
+	wantError(t, err, models.ErrTeamNotFound)
+	pr, err = b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.TeamName != "" {
+		t.Fatalf("PR team after delete = %q, want none", pr.TeamName)
+	}
+
+	// A removed user can join a team again.
+	must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "frontend", UserID: "u1", Role: models.RoleMember, IsActive: true}))
+	members, err = b.repos.User.GetTeamMembers(ctx, "frontend")
+	must(t, err)
+	if got := ids(members); !reflect.DeepEqual(got, []string{"u1", "u3"}) {
+		t.Fatalf("frontend members = %v, want [u1 u3]", got)
+	}
+}
+
+func testUsers(t *testing.T, b backend) {
+	ctx := context.Background()
+
+	_, err := b.repos.User.GetUser(ctx, "u1")
+	wantError(t, err, models.ErrUserNotFound)
+	_, err = b.repos.User.SetUserActive(ctx, "u1", false)
+	wantError(t, err, models.ErrUserNotFound)
+
+	seedTeam(t, b, "backend", "u2", "u1")
+	seedTeam(t, b, "frontend")
+
+	members, err := b.repos.User.GetTeamMembers(ctx, "backend")
+	must(t, err)
+	if got := ids(members); !reflect.DeepEqual(got, []string{"u1", "u2"}) {
+		t.Fatalf("members = %v, want [u1 u2]", got)
+	}
+
+	members, err = b.repos.User.GetTeamMembers(ctx, "frontend")
+	must(t, err)
+	if len(members) != 0 {
+		t.Fatalf("expected no members, got %v", members)
+	}
+
+	must(t, b.repos.User.CreateOrUpdateUser(ctx, &models.User{UserID: "u1", Username: "Alice B.", IsActive: true}))
+	user, err := b.repos.User.GetUser(ctx, "u1")
+	must(t, err)
+	if *user != (models.User{UserID: "u1", Username: "Alice B.", TeamName: "backend", IsActive: true}) {
+		t.Fatalf("user after upsert = %+v", *user)
+	}
+
+	user, err = b.repos.User.SetUserActive(ctx, "u1", false)
+	must(t, err)
+	if user.IsActive || user.TeamName != "backend" {
+		t.Fatalf("SetUserActive returned %+v", *user)
+	}
+
+	user, err = b.repos.User.GetUser(ctx, "u1")
+	must(t, err)
+	if user.IsActive {
+		t.Fatal("user is still active")
+	}
+}
+
+func testMemberships(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2")
+	seedTeam(t, b, "frontend", "u3")
+
+	wantError(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "missing", UserID: "u1", Role: models.RoleMember}), models.ErrTeamNotFound)
+	wantError(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "frontend", UserID: "ghost", Role: models.RoleMember}), models.ErrUserNotFound)
+	_, err := b.repos.Membership.GetMembership(ctx, "frontend", "u1")
+	wantError(t, err, models.ErrMembershipNotFound)
+
+	// u1 joins frontend as well but is inactive there; backend stays the
+	// default team because u1 joined it first.
+	must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "frontend", UserID: "u1", Role: models.RoleLead, IsActive: false}))
+	membership, err := b.repos.Membership.GetMembership(ctx, "frontend", "u1")
+	must(t, err)
+	if *membership != (models.TeamMembership{TeamName: "frontend", UserID: "u1", Role: models.RoleLead, IsActive: false}) {
+		t.Fatalf("membership = %+v", *membership)
+	}
+	user, err := b.repos.User.GetUser(ctx, "u1")
+	must(t, err)
+	if user.TeamName != "backend" {
+		t.Fatalf("default team = %q, want backend", user.TeamName)
+	}
+
+	active := func(teamName string) map[string]bool {
+		t.Helper()
+		members, err := b.repos.User.GetTeamMembers(ctx, teamName)
+		must(t, err)
+		out := map[string]bool{}
+		for _, m := range members {
+			if m.TeamName != teamName {
+				t.Fatalf("member of %s reported in %s", teamName, m.TeamName)
+			}
+			out[m.UserID] = m.IsActive
+		}
+		return out
+	}
+	if got := active("frontend"); !reflect.DeepEqual(got, map[string]bool{"u1": false, "u3": true}) {
+		t.Fatalf("frontend members = %v", got)
+	}
+	if got := active("backend"); !reflect.DeepEqual(got, map[string]bool{"u1": true, "u2": true}) {
+		t.Fatalf("backend members = %v", got)
+	}
+
+	// A user deactivated globally is inactive in every team.
+	must(t, b.repos.Membership.SaveMembership(ctx, &models.TeamMembership{TeamName: "frontend", UserID: "u1", Role: models.RoleLead, IsActive: true}))
+	_, err = b.repos.User.SetUserActive(ctx, "u1", false)
+	must(t, err)
+	if active("frontend")["u1"] || active("backend")["u1"] {
+		t.Fatal("globally inactive user is active in a team")
+	}
+
+	memberships, err := b.repos.Membership.ListTeamMemberships(ctx, "frontend")
+	must(t, err)
+	want := []models.TeamMembership{
+		{TeamName: "frontend", UserID: "u1", Role: models.RoleLead, IsActive: true},
+		{TeamName: "frontend", UserID: "u3", Role: models.RoleMember, IsActive: true},
+	}
+	if !reflect.DeepEqual(memberships, want) {
+		t.Fatalf("memberships = %+v, want %+v", memberships, want)
+	}
+
+	must(t, b.repos.Membership.DeleteMembership(ctx, "backend", "u1"))
+	wantError(t, b.repos.Membership.DeleteMembership(ctx, "backend", "u1"), models.ErrMembershipNotFound)
+	user, err = b.repos.User.GetUser(ctx, "u1")
+	must(t, err)
+	if user.TeamName != "frontend" {
+		t.Fatalf("default team after leaving backend = %q, want frontend", user.TeamName)
+	}
+
+	err = b.repos.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "x", AuthorID: "u1", TeamName: "missing", Status: models.PRStatusOpen})
+	wantError(t, err, models.ErrTeamNotFound)
+	must(t, b.repos.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "x", AuthorID: "u1", TeamName: "frontend", Status: models.PRStatusOpen}))
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.TeamName != "frontend" {
+		t.Fatalf("PR team = %q, want frontend", pr.TeamName)
+	}
+}
+
+func ids(users []models.User) []string {
+	out := []string{}
+	for _, u := range users {
+		out = append(out, u.UserID)
+	}
+	return out
+}
+
+func testPullRequests(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2", "u3")
+
+	_, err := b.repos.PR.GetPR(ctx, "pr-1")
+	wantError(t, err, models.ErrPRNotFound)
+
+	err = b.repos.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-x", Name: "x", AuthorID: "ghost", Status: models.PRStatusOpen})
+	wantError(t, err, models.ErrUserNotFound)
+
+	seedPR(t, b, "pr-1", "u1", "u3", "u2")
+	err = b.repos.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "again", AuthorID: "u1", Status: models.PRStatusOpen})
+	wantError(t, err, models.ErrPRExists)
+
+	wantError(t, b.repos.PR.AssignReviewers(ctx, "pr-1", []string{"u2"}), nil)
+	wantError(t, b.repos.PR.AssignReviewers(ctx, "pr-1", []string{"ghost"}), models.ErrNotFound)
+
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.ID != "pr-1" || pr.Name != "name-pr-1" || pr.AuthorID != "u1" || pr.Status != models.PRStatusOpen {
+		t.Fatalf("unexpected PR %+v", pr)
+	}
+	if pr.CreatedAt == nil || pr.MergedAt != nil || pr.ClosedAt != nil {
+		t.Fatalf("unexpected timestamps %+v", pr)
+	}
+	if !reflect.DeepEqual(pr.AssignedReviewers, []string{"u2", "u3"}) {
+		t.Fatalf("reviewers = %v, want [u2 u3]", pr.AssignedReviewers)
+	}
+	for _, review := range pr.Reviews {
+		if review.State != models.ReviewPending || review.AssignedAt == nil || review.DecidedAt != nil {
+			t.Fatalf("unexpected review %+v", review)
+		}
+	}
+
+	assigned, err := b.repos.PR.IsReviewerAssigned(ctx, "pr-1", "u2")
+	must(t, err)
+	if !assigned {
+		t.Fatal("u2 should be assigned")
+	}
+
+	assigned, err = b.repos.PR.IsReviewerAssigned(ctx, "pr-1", "u1")
+	must(t, err)
+	if assigned {
+		t.Fatal("u1 should not be assigned")
+	}
+
+	seedPR(t, b, "pr-2", "u3", "u2")
+	counts, err := b.repos.PR.CountOpenReviews(ctx, []string{"u1", "u2", "u3"})
+	must(t, err)
+	if !reflect.DeepEqual(counts, map[string]int{"u1": 0, "u2": 2, "u3": 1}) {
+		t.Fatalf("counts = %v", counts)
+	}
+	counts, err = b.repos.PR.CountOpenReviews(ctx, nil)
+	must(t, err)
+	if !reflect.DeepEqual(counts, map[string]int{"u2": 2, "u3": 1}) {
+		t.Fatalf("counts of all reviewers = %v", counts)
+	}
+
+	reviews, err := b.repos.PR.GetUserReviews(ctx, "u2")
+	must(t, err)
+	want := []models.PullRequestShort{
+		{ID: "pr-1", Name: "name-pr-1", AuthorID: "u1", Status: models.PRStatusOpen},
+		{ID: "pr-2", Name: "name-pr-2", AuthorID: "u3", Status: models.PRStatusOpen},
+	}
+	if !reflect.DeepEqual(reviews, want) {
+		t.Fatalf("reviews = %+v, want %+v", reviews, want)
+	}
+
+	reviews, err = b.repos.PR.GetUserReviews(ctx, "u1")
+	must(t, err)
+	if len(reviews) != 0 {
+		t.Fatalf("expected no reviews, got %+v", reviews)
+	}
+
+	must(t, b.repos.PR.RemoveReviewer(ctx, "pr-1", "u3"))
+	must(t, b.repos.PR.RemoveReviewer(ctx, "pr-1", "u3"))
+	pr, err = b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if !reflect.DeepEqual(pr.AssignedReviewers, []string{"u2"}) {
+		t.Fatalf("reviewers after remove = %v", pr.AssignedReviewers)
+	}
+
+	merged, err := b.repos.PR.MergePR(ctx, "pr-1")
+	must(t, err)
+	if merged.Status != models.PRStatusMerged || merged.MergedAt == nil {
+		t.Fatalf("unexpected merged PR %+v", merged)
+	}
+
+	again, err := b.repos.PR.MergePR(ctx, "pr-1")
+	must(t, err)
+	if !again.MergedAt.Equal(*merged.MergedAt) {
+		t.Fatal("merging twice must keep merged_at")
+	}
+
+	_, err = b.repos.PR.MergePR(ctx, "missing")
+	wantError(t, err, models.ErrPRNotFound)
+
+	counts, err = b.repos.PR.CountOpenReviews(ctx, []string{"u2"})
+	must(t, err)
+	if counts["u2"] != 1 {
+		t.Fatalf("merged PRs must not count as open reviews, got %d", counts["u2"])
+	}
+}
+
+func testReviewStates(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2")
+	seedPR(t, b, "pr-1", "u1", "u2")
+
+	wantError(t, b.repos.PR.SetReviewState(ctx, "pr-1", "u1", models.ReviewApproved), models.ErrNotAssigned)
+	wantError(t, b.repos.PR.SetReviewState(ctx, "missing", "u2", models.ReviewApproved), models.ErrNotAssigned)
+
+	must(t, b.repos.PR.SetReviewState(ctx, "pr-1", "u2", models.ReviewChangesRequested))
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if len(pr.Reviews) != 1 || pr.Reviews[0].State != models.ReviewChangesRequested || pr.Reviews[0].DecidedAt == nil {
+		t.Fatalf("unexpected reviews %+v", pr.Reviews)
+	}
+}
+
+func testPRStatus(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2", "u3")
+	seedPR(t, b, "pr-1", "u1", "u2", "u3")
+
+	must(t, b.repos.PR.SetStatus(ctx, "pr-1", models.PRStatusClosed))
+	must(t, b.repos.PR.RemoveAllReviewers(ctx, "pr-1"))
+
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.Status != models.PRStatusClosed || pr.ClosedAt == nil || len(pr.AssignedReviewers) != 0 {
+		t.Fatalf("unexpected closed PR %+v", pr)
+	}
+
+	must(t, b.repos.PR.SetStatus(ctx, "pr-1", models.PRStatusOpen))
+	pr, err = b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if pr.Status != models.PRStatusOpen || pr.ClosedAt != nil {
+		t.Fatalf("unexpected reopened PR %+v", pr)
+	}
+
+	must(t, b.repos.PR.SetStatus(ctx, "missing", models.PRStatusOpen))
+}
+
+func testAudit(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1")
+	seedPR(t, b, "pr-1", "u1")
+
+	must(t, b.repos.Audit.Record(ctx, &models.AuditEntry{PullRequestID: "pr-1", Action: "MERGE", Actor: "admin", Forced: true}))
+	wantError(t, b.repos.Audit.Record(ctx, &models.AuditEntry{PullRequestID: "missing", Action: "MERGE"}), models.ErrPRNotFound)
+}
+
+func testIdentities(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2")
+
+	_, err := b.repos.Identity.ResolveUserID(ctx, models.ProviderGitHub, "alice")
+	wantError(t, err, models.ErrIdentityNotFound)
+
+	wantError(t, b.repos.Identity.SetMapping(ctx, &models.IdentityMapping{Provider: models.ProviderGitHub, Login: "alice", UserID: "ghost"}), models.ErrUserNotFound)
+
+	must(t, b.repos.Identity.SetMapping(ctx, &models.IdentityMapping{Provider: models.ProviderGitHub, Login: "alice", UserID: "u1"}))
+	must(t, b.repos.Identity.SetMapping(ctx, &models.IdentityMapping{Provider: models.ProviderGitHub, Login: "alice", UserID: "u2"}))
+
+	userID, err := b.repos.Identity.ResolveUserID(ctx, models.ProviderGitHub, "alice")
+	must(t, err)
+	if userID != "u2" {
+		t.Fatalf("resolved %q, want u2", userID)
+	}
+
+	_, err = b.repos.Identity.ResolveUserID(ctx, models.ProviderGitLab, "alice")
+	wantError(t, err, models.ErrIdentityNotFound)
+}
+
+func testSubscriptions(t *testing.T, b backend) {
+	ctx := context.Background()
+	subs := b.repos.Subscription
+
+	_, err := subs.GetSubscription(ctx, "s1")
+	wantError(t, err, models.ErrSubscriptionNotFound)
+	wantError(t, subs.UpdateSubscription(ctx, &models.WebhookSubscription{ID: "s1", Events: []string{}}), models.ErrSubscriptionNotFound)
+	wantError(t, subs.DeleteSubscription(ctx, "s1"), models.ErrSubscriptionNotFound)
+	wantError(t, subs.RecordDelivery(ctx, &models.WebhookDelivery{SubscriptionID: "s1", EventID: "e1", EventType: models.EventPRCreated, Attempt: 1}), models.ErrSubscriptionNotFound)
+
+	all := &models.WebhookSubscription{ID: "s1", URL: "http://a", Secret: "x", Events: []string{}, IsActive: true}
+	merged := &models.WebhookSubscription{ID: "s2", URL: "http://b", Secret: "y", Events: []string{models.EventPRMerged}, IsActive: true}
+	inactive := &models.WebhookSubscription{ID: "s3", URL: "http://c", Secret: "z", Events: []string{}, IsActive: false}
+	for _, sub := range []*models.WebhookSubscription{all, merged, inactive} {
+		must(t, subs.CreateSubscription(ctx, sub))
+		if sub.CreatedAt == nil {
+			t.Fatalf("CreateSubscription must set created_at on %s", sub.ID)
+		}
+	}
+	wantError(t, subs.CreateSubscription(ctx, &models.WebhookSubscription{ID: "s1", URL: "http://a", Secret: "x", Events: []string{}}), nil)
+
+	got, err := subs.GetSubscription(ctx, "s2")
+	must(t, err)
+	if got.URL != "http://b" || got.Secret != "y" || !reflect.DeepEqual(got.Events, []string{models.EventPRMerged}) || !got.IsActive {
+		t.Fatalf("unexpected subscription %+v", got)
+	}
+
+	list, err := subs.ListSubscriptions(ctx)
+	must(t, err)
+	if len(list) != 3 {
+		t.Fatalf("expected 3 subscriptions, got %d", len(list))
+	}
+
+	active, err := subs.ListActiveForEvent(ctx, models.EventPRMerged)
+	must(t, err)
+	if got := subscriptionIDs(active); !reflect.DeepEqual(got, []string{"s1", "s2"}) {
+		t.Fatalf("active for pr.merged = %v", got)
+	}
+
+	active, err = subs.ListActiveForEvent(ctx, models.EventPRCreated)
+	must(t, err)
+	if got := subscriptionIDs(active); !reflect.DeepEqual(got, []string{"s1"}) {
+		t.Fatalf("active for pr.created = %v", got)
+	}
+
+	merged.IsActive = false
+	must(t, subs.UpdateSubscription(ctx, merged))
+	active, err = subs.ListActiveForEvent(ctx, models.EventPRMerged)
+	must(t, err)
+	if got := subscriptionIDs(active); !reflect.DeepEqual(got, []string{"s1"}) {
+		t.Fatalf("active after update = %v", got)
+	}
+
+	must(t, subs.RecordDelivery(ctx, &models.WebhookDelivery{SubscriptionID: "s1", EventID: "e1", EventType: models.EventPRCreated, Attempt: 1, StatusCode: 200, Delivered: true}))
+	must(t, subs.DeleteSubscription(ctx, "s1"))
+	_, err = subs.GetSubscription(ctx, "s1")
+	wantError(t, err, models.ErrSubscriptionNotFound)
+}
+
+func subscriptionIDs(subs []models.WebhookSubscription) []string {
+	out := []string{}
+	for _, s := range subs {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func testWebhookRetries(t *testing.T, b backend) {
+	ctx := context.Background()
+	subs := b.repos.Subscription
+
+	occurred := time.Now().UTC().Truncate(time.Second)
+	event := models.Event{ID: "e1", Type: models.EventPRCreated, OccurredAt: occurred, Data: []byte(`{"id":"e1"}`)}
+	wantError(t, subs.ScheduleRetry(ctx, &models.WebhookRetry{Subscription: models.WebhookSubscription{ID: "s1"}, Event: event, Attempt: 2, NextAttemptAt: occurred}), models.ErrSubscriptionNotFound)
+
+	active := &models.WebhookSubscription{ID: "s1", URL: "http://a", Secret: "x", Events: []string{}, IsActive: true}
+	inactive := &models.WebhookSubscription{ID: "s2", URL: "http://b", Secret: "y", Events: []string{}, IsActive: false}
+	must(t, subs.CreateSubscription(ctx, active))
+	must(t, subs.CreateSubscription(ctx, inactive))
+
+	past := time.Now().UTC().Add(-time.Minute)
+	later := &models.WebhookRetry{Subscription: *active, Event: event, Attempt: 2, NextAttemptAt: past}
+	sooner := &models.WebhookRetry{Subscription: *active, Event: event, Attempt: 3, NextAttemptAt: past.Add(-time.Second)}
+	future := &models.WebhookRetry{Subscription: *active, Event: event, Attempt: 2, NextAttemptAt: time.Now().UTC().Add(time.Hour)}
+	paused := &models.WebhookRetry{Subscription: *inactive, Event: event, Attempt: 2, NextAttemptAt: past}
+	for _, retry := range []*models.WebhookRetry{later, sooner, future, paused} {
+		must(t, subs.ScheduleRetry(ctx, retry))
+		if retry.ID == 0 {
+			t.Fatal("ScheduleRetry must set the id")
+		}
+	}
+
+	claimed, err := subs.ClaimRetries(ctx, 10, time.Minute)
+	must(t, err)
+	if got := retryIDs(claimed); !reflect.DeepEqual(got, []int64{sooner.ID, later.ID}) {
+		t.Fatalf("claimed retries = %v, want due retries of active subscriptions oldest first", got)
+	}
+	var payload map[string]string
+	must(t, json.Unmarshal(claimed[0].Event.Data, &payload))
+	if claimed[0].Subscription.URL != "http://a" || claimed[0].Attempt != 3 || payload["id"] != "e1" || !claimed[0].Event.OccurredAt.Equal(occurred) {
+		t.Fatalf("unexpected retry %+v", claimed[0])
+	}
+
+	claimed, err = subs.ClaimRetries(ctx, 10, time.Minute)
+	must(t, err)
+	if len(claimed) != 0 {
+		t.Fatalf("leased retries must not be claimed again, got %v", retryIDs(claimed))
+	}
+
+	// Rescheduling releases the lease; deleting drops the retry for good.
+	sooner.Attempt = 4
+	must(t, subs.RescheduleRetry(ctx, sooner))
+	must(t, subs.DeleteRetry(ctx, later.ID))
+	claimed, err = subs.ClaimRetries(ctx, 10, time.Minute)
+	must(t, err)
+	if got := retryIDs(claimed); !reflect.DeepEqual(got, []int64{sooner.ID}) || claimed[0].Attempt != 4 {
+		t.Fatalf("claim after reschedule = %+v", claimed)
+	}
+
+	must(t, subs.DeleteSubscription(ctx, "s2"))
+	inactive.ID = "s2"
+	inactive.IsActive = true
+	must(t, subs.CreateSubscription(ctx, inactive))
+	claimed, err = subs.ClaimRetries(ctx, 10, -time.Minute)
+	must(t, err)
+	if len(claimed) != 0 {
+		t.Fatalf("deleting a subscription must drop its retries, got %v", retryIDs(claimed))
+	}
+}
+
+func retryIDs(retries []models.WebhookRetry) []int64 {
+	out := []int64{}
+	for _, r := range retries {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func testOutbox(t *testing.T, b backend) {
+	ctx := context.Background()
+	outbox := b.repos.Outbox
+
+	stats, err := outbox.Stats(ctx)
+	must(t, err)
+	if stats.Pending != 0 || stats.LagSeconds != 0 {
+		t.Fatalf("empty outbox stats = %+v", stats)
+	}
+
+	occurred := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"e1", "e2", "e3"} {
+		must(t, outbox.Enqueue(ctx, &models.Event{ID: id, Type: models.EventPRCreated, OccurredAt: occurred, Data: []byte(`{"id":"` + id + `"}`)}))
+	}
+	wantError(t, outbox.Enqueue(ctx, &models.Event{ID: "e1", Type: models.EventPRCreated, OccurredAt: occurred, Data: []byte(`{}`)}), nil)
+
+	stats, err = outbox.Stats(ctx)
+	must(t, err)
+	if stats.Pending != 3 {
+		t.Fatalf("pending = %d, want 3", stats.Pending)
+	}
+
+	batch, err := outbox.Claim(ctx, 2, time.Minute)
+	must(t, err)
+	if got := eventIDs(batch); !reflect.DeepEqual(got, []string{"e1", "e2"}) {
+		t.Fatalf("first claim = %v", got)
+	}
+	var payload map[string]string
+	must(t, json.Unmarshal(batch[0].Data, &payload))
+	if batch[0].Type != models.EventPRCreated || payload["id"] != "e1" || !batch[0].OccurredAt.Equal(occurred) {
+		t.Fatalf("unexpected event %+v", batch[0])
+	}
+
+	batch, err = outbox.Claim(ctx, 10, time.Minute)
+	must(t, err)
+	if got := eventIDs(batch); !reflect.DeepEqual(got, []string{"e3"}) {
+		t.Fatalf("leased events must not be claimed again, got %v", got)
+	}
+
+	must(t, outbox.MarkDispatched(ctx, "e1"))
+	stats, err = outbox.Stats(ctx)
+	must(t, err)
+	if stats.Pending != 2 {
+		t.Fatalf("pending after dispatch = %d, want 2", stats.Pending)
+	}
+
+	// An expired lease makes the event claimable again.
+	must(t, outbox.Enqueue(ctx, &models.Event{ID: "e4", Type: models.EventPRMerged, OccurredAt: occurred, Data: []byte(`{}`)}))
+	batch, err = outbox.Claim(ctx, 10, -time.Minute)
+	must(t, err)
+	if got := eventIDs(batch); !reflect.DeepEqual(got, []string{"e4"}) {
+		t.Fatalf("claim with expired lease = %v", got)
+	}
+	batch, err = outbox.Claim(ctx, 10, time.Minute)
+	must(t, err)
+	if got := eventIDs(batch); !reflect.DeepEqual(got, []string{"e4"}) {
+		t.Fatalf("expired lease must be reclaimable, got %v", got)
+	}
+}
+
+func eventIDs(events []models.Event) []string {
+	out := []string{}
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func testTransactions(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1", "u2")
+
+	boom := errors.New("boom")
+	err := b.tx.WithTx(ctx, func(tx *Repos) error {
+		if err := tx.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "x", AuthorID: "u1", Status: models.PRStatusOpen}); err != nil {
+			return err
+		}
+		if _, err := tx.PR.GetPR(ctx, "pr-1"); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("WithTx returned %v, want %v", err, boom)
+	}
+
+	_, err = b.repos.PR.GetPR(ctx, "pr-1")
+	wantError(t, err, models.ErrPRNotFound)
+
+	err = b.tx.WithTx(ctx, func(tx *Repos) error {
+		if err := tx.PR.CreatePR(ctx, &models.PullRequest{ID: "pr-1", Name: "x", AuthorID: "u1", Status: models.PRStatusOpen}); err != nil {
+			return err
+		}
+		return tx.PR.AssignReviewers(ctx, "pr-1", []string{"u2"})
+	})
+	must(t, err)
+
+	pr, err := b.repos.PR.GetPR(ctx, "pr-1")
+	must(t, err)
+	if !reflect.DeepEqual(pr.AssignedReviewers, []string{"u2"}) {
+		t.Fatalf("committed reviewers = %v", pr.AssignedReviewers)
+	}
+
+	_, err = b.repos.PR.GetPRForUpdate(ctx, "missing")
+	wantError(t, err, models.ErrPRNotFound)
+}
+
+// testRowLock checks that a transaction which locked a PR with
+// GetPRForUpdate makes a second one wait and then see its changes.
+func testRowLock(t *testing.T, b backend) {
+	ctx := context.Background()
+	seedTeam(t, b, "backend", "u1")
+	seedPR(t, b, "pr-1", "u1")
+
+	t.Run("PR", func(t *testing.T) {
+		checkRowLock(t, b,
+			func(tx *Repos) error {
+				_, err := tx.PR.GetPRForUpdate(ctx, "pr-1")
+				return err
+			},
+			func(tx *Repos) error {
+				return tx.PR.SetStatus(ctx, "pr-1", models.PRStatusClosed)
+			},
+			func(tx *Repos) error {
+				pr, err := tx.PR.GetPRForUpdate(ctx, "pr-1")
+				if err == nil && pr.Status != models.PRStatusClosed {
+					err = fmt.Errorf("second transaction saw status %s, want CLOSED", pr.Status)
+				}
+				return err
+			})
+	})
+
+	t.Run("TeamSettings", func(t *testing.T) {
+		checkRowLock(t, b,
+			func(tx *Repos) error {
+				_, err := tx.Team.GetSettingsForUpdate(ctx, "backend")
+				return err
+			},
+			func(tx *Repos) error {
+				return tx.Team.UpdateSettings(ctx, &models.TeamSettings{TeamName: "backend", ReviewersPerPR: 3, MinReviewers: 1})
+			},
+			func(tx *Repos) error {
+				settings, err := tx.Team.GetSettingsForUpdate(ctx, "backend")
+				if err == nil && settings.ReviewersPerPR != 3 {
+					err = fmt.Errorf("second transaction saw reviewers_per_pr %d, want 3", settings.ReviewersPerPR)
+				}
+				return err
+			})
+	})
+
+	t.Run("RoundRobinCursor", func(t *testing.T) {
+		checkRowLock(t, b,
+			func(tx *Repos) error {
+				_, err := tx.Team.GetRoundRobinCursorForUpdate(ctx, "backend")
+				return err
+			},
+			func(tx *Repos) error {
+				return tx.Team.SetRoundRobinCursor(ctx, "backend", "u1")
+			},
+			func(tx *Repos) error {
+				cursor, err := tx.Team.GetRoundRobinCursorForUpdate(ctx, "backend")
+				if err == nil && cursor != "u1" {
+					err = fmt.Errorf("second transaction saw cursor %q, want u1", cursor)
+				}
+				return err
+			})
+	})
+}
+
+// checkRowLock holds the lock taken by lock in one transaction and checks
+// that a second transaction running read waits for it and then sees what
+// write stored.
+func checkRowLock(t *testing.T, b backend, lock, write, read func(tx *Repos) error) {
+	t.Helper()
+	ctx := context.Background()
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	first := make(chan error, 1)
+	go func() {
+		first <- b.tx.WithTx(ctx, func(tx *Repos) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+			close(locked)
+			<-release
+			return write(tx)
+		})
+	}()
+
+	select {
+	case <-locked:
+	case err := <-first:
+		t.Fatalf("first transaction failed: %v", err)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		second <- b.tx.WithTx(ctx, read)
+	}()
+
+	select {
+	case <-second:
+		t.Fatal("second transaction did not wait for the row lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	must(t, <-first)
+	must(t, <-second)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBTX is implemented by both *pgxpool.Pool and pgx.Tx, so repositories can
+// run either on the pool or inside a transaction.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type Repos struct {
+	PR           PRRepo
+	User         UserRepo
+	Membership   MembershipRepo
+	Team         TeamRepo
+	Audit        AuditRepo
+	Identity     IdentityRepo
+	Subscription SubscriptionRepo
+	Outbox       OutboxRepo
+}
+
+// NewRepos returns PostgreSQL repositories that run on the pool.
+func NewRepos(pool *pgxpool.Pool) *Repos {
+	return newRepos(pool)
+}
+
+func newRepos(db DBTX) *Repos {
+	return &Repos{
+		PR:           &PRRepository{db: db},
+		User:         &UserRepository{db: db},
+		Membership:   &MembershipRepository{db: db},
+		Team:         &TeamRepository{db: db},
+		Audit:        &AuditRepository{db: db},
+		Identity:     &IdentityRepository{db: db},
+		Subscription: &SubscriptionRepository{db: db},
+		Outbox:       &OutboxRepository{db: db},
+	}
+}
+
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// WithTx runs fn with repositories bound to a single transaction. The
+// transaction is committed if fn returns nil and rolled back otherwise.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Repos) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(newRepos(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes the repositories translate into domain errors.
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func hasPgCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func isForeignKeyViolation(err error) bool {
+	return hasPgCode(err, pgForeignKeyViolation)
+}
+
+func isUniqueViolation(err error) bool {
+	return hasPgCode(err, pgUniqueViolation)
+}
+
+// violatedConstraint returns the name of the constraint err violates, if any.
+func violatedConstraint(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdentityRepository struct {
+	db DBTX
+}
+
+func NewIdentityRepository(pool *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{db: pool}
+}
+
+func (r *IdentityRepository) SetMapping(ctx context.Context, mapping *models.IdentityMapping) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO identity_mappings (provider, external_login, user_id)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (provider, external_login) DO UPDATE
+		 SET user_id = $3`,
+		mapping.Provider, mapping.Login, mapping.UserID)
+	if isForeignKeyViolation(err) {
+		return models.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save %s identity %s: %w", mapping.Provider, mapping.Login, err)
+	}
+	return nil
+}
+
+func (r *IdentityRepository) ResolveUserID(ctx context.Context, provider string, login string) (string, error) {
+	var userID string
+	err := r.db.QueryRow(ctx,
+		"SELECT user_id FROM identity_mappings WHERE provider = $1 AND external_login = $2",
+		provider, login).Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrIdentityNotFound
+		}
+		return "", fmt.Errorf("failed to resolve %s identity %s: %w", provider, login, err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MembershipRepository struct {
+	db DBTX
+}
+
+func NewMembershipRepository(pool *pgxpool.Pool) *MembershipRepository {
+	return &MembershipRepository{db: pool}
+}
+
+func (r *MembershipRepository) SaveMembership(ctx context.Context, membership *models.TeamMembership) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO team_memberships (team_name, user_id, role, is_active)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (team_name, user_id) DO UPDATE
+		 SET role = $3, is_active = $4`,
+		membership.TeamName, membership.UserID, membership.Role, membership.IsActive)
+	if isForeignKeyViolation(err) {
+		if violatedConstraint(err) == "team_memberships_team_name_fkey" {
+			return models.ErrTeamNotFound
+		}
+		return models.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add %s to team %s: %w", membership.UserID, membership.TeamName, err)
+	}
+	return nil
+}
+
+func (r *MembershipRepository) GetMembership(ctx context.Context, teamName string, userID string) (*models.TeamMembership, error) {
+	membership := &models.TeamMembership{TeamName: teamName, UserID: userID}
+	err := r.db.QueryRow(ctx,
+		"SELECT role, is_active FROM team_memberships WHERE team_name = $1 AND user_id = $2",
+		teamName, userID).Scan(&membership.Role, &membership.IsActive)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrMembershipNotFound
+		}
+		return nil, fmt.Errorf("failed to get membership of %s in team %s: %w", userID, teamName, err)
+	}
+
+	return membership, nil
+}
+
+func (r *MembershipRepository) ListTeamMemberships(ctx context.Context, teamName string) ([]models.TeamMembership, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT team_name, user_id, role, is_active FROM team_memberships WHERE team_name = $1 ORDER BY user_id",
+		teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memberships of team %s: %w", teamName, err)
+	}
+	defer rows.Close()
+
+	var memberships []models.TeamMembership
+	for rows.Next() {
+		var membership models.TeamMembership
+		if err := rows.Scan(&membership.TeamName, &membership.UserID, &membership.Role, &membership.IsActive); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, rows.Err()
+}
+
+func (r *MembershipRepository) DeleteMembership(ctx context.Context, teamName string, userID string) error {
+	tag, err := r.db.Exec(ctx,
+		"DELETE FROM team_memberships WHERE team_name = $1 AND user_id = $2",
+		teamName, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from team %s: %w", userID, teamName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrMembershipNotFound
+	}
+	return nil
+}
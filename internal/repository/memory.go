@@ -0,0 +1,1127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+)
+
+// MemoryStore keeps all data in process memory. It implements the same
+// repository interfaces and errors as the PostgreSQL repositories and is
+// meant for tests that should not need a database.
+//
+// Transactions are serialised: WithTx works on a copy of the data that
+// replaces the current state only if fn succeeds. Repositories returned by
+// Repos must not be used from inside fn, use the ones passed to it instead.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state *memState
+	repos *Repos
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{state: newMemState()}
+	s.repos = newMemRepos(func(fn func(st *memState) error) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return fn(s.state)
+	})
+	return s
+}
+
+// Repos returns repositories that run outside of a transaction.
+func (s *MemoryStore) Repos() *Repos {
+	return s.repos
+}
+
+func (s *MemoryStore) WithTx(ctx context.Context, fn func(tx *Repos) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	work := s.state.clone()
+	err := fn(newMemRepos(func(fn func(st *memState) error) error {
+		return fn(work)
+	}))
+	if err != nil {
+		return err
+	}
+
+	s.state = work
+	return nil
+}
+
+// memAccess runs fn against the state a repository is bound to.
+type memAccess func(fn func(st *memState) error) error
+
+func newMemRepos(access memAccess) *Repos {
+	return &Repos{
+		PR:           &memPRRepo{access: access},
+		User:         &memUserRepo{access: access},
+		Membership:   &memMembershipRepo{access: access},
+		Team:         &memTeamRepo{access: access},
+		Audit:        &memAuditRepo{access: access},
+		Identity:     &memIdentityRepo{access: access},
+		Subscription: &memSubscriptionRepo{access: access},
+		Outbox:       &memOutboxRepo{access: access},
+	}
+}
+
+type memPR struct {
+	pr      models.PullRequest
+	reviews map[string]models.Review
+}
+
+type memMembershipKey struct {
+	teamName string
+	userID   string
+}
+
+// memMembership keeps the order memberships were created in, which decides a
+// user's default team like joined_at does in PostgreSQL.
+type memMembership struct {
+	membership models.TeamMembership
+	seq        int64
+}
+
+type memIdentityKey struct {
+	provider string
+	login    string
+}
+
+type memOutboxRow struct {
+	id           int64
+	event        models.Event
+	createdAt    time.Time
+	lockedUntil  *time.Time
+	attempts     int
+	dispatchedAt *time.Time
+}
+
+type memRetryRow struct {
+	id             int64
+	subscriptionID string
+	event          models.Event
+	attempt        int
+	nextAttemptAt  time.Time
+	lockedUntil    *time.Time
+}
+
+type memState struct {
+	teams      map[string]models.TeamSettings
+	roundRobin map[string]string
+	users      map[string]models.User
+	members    map[memMembershipKey]memMembership
+	memberSeq  int64
+	prs        map[string]*memPR
+	audit      []models.AuditEntry
+	identities map[memIdentityKey]string
+	subs       map[string]models.WebhookSubscription
+	deliveries []models.WebhookDelivery
+	retries    []*memRetryRow
+	retrySeq   int64
+	outbox     []*memOutboxRow
+	outboxSeq  int64
+}
+
+func newMemState() *memState {
+	return &memState{
+		teams:      make(map[string]models.TeamSettings),
+		roundRobin: make(map[string]string),
+		users:      make(map[string]models.User),
+		members:    make(map[memMembershipKey]memMembership),
+		prs:        make(map[string]*memPR),
+		identities: make(map[memIdentityKey]string),
+		subs:       make(map[string]models.WebhookSubscription),
+	}
+}
+
+func (st *memState) clone() *memState {
+	c := newMemState()
+	for k, v := range st.teams {
+		c.teams[k] = v
+	}
+	for k, v := range st.roundRobin {
+		c.roundRobin[k] = v
+	}
+	for k, v := range st.users {
+		c.users[k] = v
+	}
+	for k, v := range st.members {
+		c.members[k] = v
+	}
+	c.memberSeq = st.memberSeq
+	for k, v := range st.prs {
+		reviews := make(map[string]models.Review, len(v.reviews))
+		for id, review := range v.reviews {
+			reviews[id] = review
+		}
+		c.prs[k] = &memPR{pr: v.pr, reviews: reviews}
+	}
+	for k, v := range st.identities {
+		c.identities[k] = v
+	}
+	for k, v := range st.subs {
+		c.subs[k] = copySubscription(v)
+	}
+	c.audit = append(c.audit, st.audit...)
+	c.deliveries = append(c.deliveries, st.deliveries...)
+	for _, row := range st.retries {
+		r := *row
+		c.retries = append(c.retries, &r)
+	}
+	c.retrySeq = st.retrySeq
+	for _, row := range st.outbox {
+		r := *row
+		c.outbox = append(c.outbox, &r)
+	}
+	c.outboxSeq = st.outboxSeq
+	return c
+}
+
+func memNow() *time.Time {
+	now := time.Now().UTC()
+	return &now
+}
+
+type memTeamRepo struct {
+	access memAccess
+}
+
+func (r *memTeamRepo) CreateTeam(ctx context.Context, team *models.Team) error {
+	return r.access(func(st *memState) error {
+		if _, exists := st.teams[team.TeamName]; exists {
+			return models.ErrTeamExists
+		}
+
+		st.teams[team.TeamName] = models.TeamSettings{
+			TeamName:               team.TeamName,
+			ReviewerStrategy:       team.ReviewerStrategy,
+			ReviewersPerPR:         2,
+			MinReviewers:           1,
+			AllowFewerThanRequired: true,
+		}
+		return nil
+	})
+}
+
+func (r *memTeamRepo) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+	var team *models.Team
+	err := r.access(func(st *memState) error {
+		settings, ok := st.teams[teamName]
+		if !ok {
+			return models.ErrTeamNotFound
+		}
+
+		team = &models.Team{
+			TeamName:         teamName,
+			Members:          []models.TeamMember{},
+			ReviewerStrategy: settings.ReviewerStrategy,
+			Archived:         settings.Archived,
+		}
+		for _, user := range st.teamMembers(teamName) {
+			team.Members = append(team.Members, models.TeamMember{
+				UserID:   user.UserID,
+				Username: user.Username,
+				IsActive: user.IsActive,
+				Role:     st.members[memMembershipKey{teamName, user.UserID}].membership.Role,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (r *memTeamRepo) GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	var settings models.TeamSettings
+	err := r.access(func(st *memState) error {
+		var ok bool
+		settings, ok = st.teams[teamName]
+		if !ok {
+			return models.ErrTeamNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (r *memTeamRepo) GetSettingsForUpdate(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	return r.GetSettings(ctx, teamName)
+}
+
+func (r *memTeamRepo) UpdateSettings(ctx context.Context, settings *models.TeamSettings) error {
+	return r.access(func(st *memState) error {
+		current, ok := st.teams[settings.TeamName]
+		if !ok {
+			return models.ErrTeamNotFound
+		}
+		updated := *settings
+		updated.Archived = current.Archived
+		st.teams[settings.TeamName] = updated
+		return nil
+	})
+}
+
+func (r *memTeamRepo) GetRoundRobinCursorForUpdate(ctx context.Context, teamName string) (string, error) {
+	var userID string
+	err := r.access(func(st *memState) error {
+		if _, ok := st.teams[teamName]; !ok {
+			return models.ErrTeamNotFound
+		}
+		userID = st.roundRobin[teamName]
+		return nil
+	})
+	return userID, err
+}
+
+func (r *memTeamRepo) SetRoundRobinCursor(ctx context.Context, teamName string, userID string) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.teams[teamName]; !ok {
+			return models.ErrTeamNotFound
+		}
+		st.roundRobin[teamName] = userID
+		return nil
+	})
+}
+
+func (r *memTeamRepo) RenameTeam(ctx context.Context, oldName string, newName string) error {
+	return r.access(func(st *memState) error {
+		settings, ok := st.teams[oldName]
+		if !ok {
+			return models.ErrTeamNotFound
+		}
+		if oldName == newName {
+			return nil
+		}
+		if _, exists := st.teams[newName]; exists {
+			return models.ErrTeamExists
+		}
+
+		delete(st.teams, oldName)
+		settings.TeamName = newName
+		st.teams[newName] = settings
+		if cursor, ok := st.roundRobin[oldName]; ok {
+			delete(st.roundRobin, oldName)
+			st.roundRobin[newName] = cursor
+		}
+		for key, m := range st.members {
+			if key.teamName == oldName {
+				delete(st.members, key)
+				m.membership.TeamName = newName
+				st.members[memMembershipKey{newName, key.userID}] = m
+			}
+		}
+		for _, pr := range st.prs {
+			if pr.pr.TeamName == oldName {
+				pr.pr.TeamName = newName
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memTeamRepo) ArchiveTeam(ctx context.Context, teamName string) error {
+	return r.access(func(st *memState) error {
+		settings, ok := st.teams[teamName]
+		if !ok {
+			return models.ErrTeamNotFound
+		}
+		settings.Archived = true
+		st.teams[teamName] = settings
+		return nil
+	})
+}
+
+func (r *memTeamRepo) DeleteTeam(ctx context.Context, teamName string) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.teams[teamName]; !ok {
+			return models.ErrTeamNotFound
+		}
+		if len(st.teamMembers(teamName)) > 0 {
+			return models.ErrTeamNotEmpty
+		}
+		delete(st.teams, teamName)
+		delete(st.roundRobin, teamName)
+		for _, pr := range st.prs {
+			if pr.pr.TeamName == teamName {
+				pr.pr.TeamName = ""
+			}
+		}
+		return nil
+	})
+}
+
+type memUserRepo struct {
+	access memAccess
+}
+
+func (r *memUserRepo) CreateOrUpdateUser(ctx context.Context, user *models.User) error {
+	return r.access(func(st *memState) error {
+		st.users[user.UserID] = models.User{
+			UserID:   user.UserID,
+			Username: user.Username,
+			IsActive: user.IsActive,
+		}
+		return nil
+	})
+}
+
+func (r *memUserRepo) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := r.access(func(st *memState) error {
+		var ok bool
+		user, ok = st.users[userID]
+		if !ok {
+			return models.ErrUserNotFound
+		}
+		user.TeamName = st.defaultTeam(userID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *memUserRepo) GetTeamMembers(ctx context.Context, teamName string) ([]models.User, error) {
+	var users []models.User
+	err := r.access(func(st *memState) error {
+		users = st.teamMembers(teamName)
+		return nil
+	})
+	return users, err
+}
+
+func (r *memUserRepo) SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error) {
+	var user models.User
+	err := r.access(func(st *memState) error {
+		var ok bool
+		user, ok = st.users[userID]
+		if !ok {
+			return models.ErrUserNotFound
+		}
+		user.IsActive = isActive
+		st.users[userID] = user
+		user.TeamName = st.defaultTeam(userID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (st *memState) teamMembers(teamName string) []models.User {
+	var users []models.User
+	for key, m := range st.members {
+		if key.teamName != teamName {
+			continue
+		}
+		user := st.users[key.userID]
+		user.TeamName = teamName
+		user.IsActive = user.IsActive && m.membership.IsActive
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UserID < users[j].UserID
+	})
+	return users
+}
+
+// defaultTeam returns the team userID joined first, or "" without any.
+func (st *memState) defaultTeam(userID string) string {
+	var teamName string
+	var first int64
+	for key, m := range st.members {
+		if key.userID == userID && (teamName == "" || m.seq < first) {
+			teamName, first = key.teamName, m.seq
+		}
+	}
+	return teamName
+}
+
+type memMembershipRepo struct {
+	access memAccess
+}
+
+func (r *memMembershipRepo) SaveMembership(ctx context.Context, membership *models.TeamMembership) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.teams[membership.TeamName]; !ok {
+			return models.ErrTeamNotFound
+		}
+		if _, ok := st.users[membership.UserID]; !ok {
+			return models.ErrUserNotFound
+		}
+
+		key := memMembershipKey{membership.TeamName, membership.UserID}
+		m, exists := st.members[key]
+		if !exists {
+			st.memberSeq++
+			m.seq = st.memberSeq
+		}
+		m.membership = *membership
+		st.members[key] = m
+		return nil
+	})
+}
+
+func (r *memMembershipRepo) GetMembership(ctx context.Context, teamName string, userID string) (*models.TeamMembership, error) {
+	var membership models.TeamMembership
+	err := r.access(func(st *memState) error {
+		m, ok := st.members[memMembershipKey{teamName, userID}]
+		if !ok {
+			return models.ErrMembershipNotFound
+		}
+		membership = m.membership
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+func (r *memMembershipRepo) ListTeamMemberships(ctx context.Context, teamName string) ([]models.TeamMembership, error) {
+	var memberships []models.TeamMembership
+	err := r.access(func(st *memState) error {
+		for key, m := range st.members {
+			if key.teamName == teamName {
+				memberships = append(memberships, m.membership)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].UserID < memberships[j].UserID
+	})
+	return memberships, err
+}
+
+func (r *memMembershipRepo) DeleteMembership(ctx context.Context, teamName string, userID string) error {
+	return r.access(func(st *memState) error {
+		key := memMembershipKey{teamName, userID}
+		if _, ok := st.members[key]; !ok {
+			return models.ErrMembershipNotFound
+		}
+		delete(st.members, key)
+		return nil
+	})
+}
+
+type memPRRepo struct {
+	access memAccess
+}
+
+func (r *memPRRepo) CreatePR(ctx context.Context, pr *models.PullRequest) error {
+	return r.access(func(st *memState) error {
+		if _, exists := st.prs[pr.ID]; exists {
+			return models.ErrPRExists
+		}
+
+		if _, ok := st.users[pr.AuthorID]; !ok {
+			return models.ErrUserNotFound
+		}
+
+		if _, ok := st.teams[pr.TeamName]; pr.TeamName != "" && !ok {
+			return models.ErrTeamNotFound
+		}
+
+		st.prs[pr.ID] = &memPR{
+			pr: models.PullRequest{
+				ID:        pr.ID,
+				Name:      pr.Name,
+				AuthorID:  pr.AuthorID,
+				TeamName:  pr.TeamName,
+				Status:    pr.Status,
+				CreatedAt: memNow(),
+			},
+			reviews: make(map[string]models.Review),
+		}
+		return nil
+	})
+}
+
+func (r *memPRRepo) AssignReviewers(ctx context.Context, prID string, reviewerIDs []string) error {
+	return r.access(func(st *memState) error {
+		for _, reviewerID := range reviewerIDs {
+			pr, ok := st.prs[prID]
+			if _, known := st.users[reviewerID]; !ok || !known {
+				return models.ErrNotFound.WithMessage("PR or reviewer not found")
+			}
+			if _, dup := pr.reviews[reviewerID]; dup {
+				return fmt.Errorf("reviewer %s is already assigned to %s", reviewerID, prID)
+			}
+
+			pr.reviews[reviewerID] = models.Review{
+				ReviewerID: reviewerID,
+				State:      models.ReviewPending,
+				AssignedAt: memNow(),
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memPRRepo) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	var pr *models.PullRequest
+	err := r.access(func(st *memState) error {
+		var err error
+		pr, err = st.getPR(prID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// GetPRForUpdate needs no row lock here: transactions on a MemoryStore are
+// already serialised.
+func (r *memPRRepo) GetPRForUpdate(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return r.GetPR(ctx, prID)
+}
+
+func (st *memState) getPR(prID string) (*models.PullRequest, error) {
+	stored, ok := st.prs[prID]
+	if !ok {
+		return nil, models.ErrPRNotFound
+	}
+
+	pr := stored.pr
+	pr.AssignedReviewers = []string{}
+	pr.Reviews = []models.Review{}
+	for _, review := range stored.reviews {
+		pr.Reviews = append(pr.Reviews, review)
+	}
+
+	sort.Slice(pr.Reviews, func(i, j int) bool {
+		return pr.Reviews[i].ReviewerID < pr.Reviews[j].ReviewerID
+	})
+	for _, review := range pr.Reviews {
+		pr.AssignedReviewers = append(pr.AssignedReviewers, review.ReviewerID)
+	}
+
+	return &pr, nil
+}
+
+func (r *memPRRepo) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	var pr *models.PullRequest
+	err := r.access(func(st *memState) error {
+		stored, ok := st.prs[prID]
+		if !ok {
+			return models.ErrPRNotFound
+		}
+
+		if stored.pr.Status != models.PRStatusMerged {
+			stored.pr.Status = models.PRStatusMerged
+			stored.pr.MergedAt = memNow()
+		}
+
+		var err error
+		pr, err = st.getPR(prID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (r *memPRRepo) GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
+	var prs []models.PullRequestShort
+	err := r.access(func(st *memState) error {
+		for _, stored := range st.prs {
+			if _, ok := stored.reviews[userID]; !ok {
+				continue
+			}
+			prs = append(prs, models.PullRequestShort{
+				ID:       stored.pr.ID,
+				Name:     stored.pr.Name,
+				AuthorID: stored.pr.AuthorID,
+				Status:   stored.pr.Status,
+			})
+		}
+		return nil
+	})
+
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].ID < prs[j].ID
+	})
+	return prs, err
+}
+
+func (r *memPRRepo) RemoveReviewer(ctx context.Context, prID string, reviewerID string) error {
+	return r.access(func(st *memState) error {
+		if stored, ok := st.prs[prID]; ok {
+			delete(stored.reviews, reviewerID)
+		}
+		return nil
+	})
+}
+
+func (r *memPRRepo) IsReviewerAssigned(ctx context.Context, prID string, reviewerID string) (bool, error) {
+	var assigned bool
+	err := r.access(func(st *memState) error {
+		if stored, ok := st.prs[prID]; ok {
+			_, assigned = stored.reviews[reviewerID]
+		}
+		return nil
+	})
+	return assigned, err
+}
+
+func (r *memPRRepo) CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(userIDs))
+	for _, id := range userIDs {
+		counts[id] = 0
+	}
+
+	err := r.access(func(st *memState) error {
+		for _, stored := range st.prs {
+			if stored.pr.Status != models.PRStatusOpen {
+				continue
+			}
+			for reviewerID := range stored.reviews {
+				if _, ok := counts[reviewerID]; ok || userIDs == nil {
+					counts[reviewerID]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (r *memPRRepo) SetReviewState(ctx context.Context, prID string, reviewerID string, state string) error {
+	return r.access(func(st *memState) error {
+		stored, ok := st.prs[prID]
+		if !ok {
+			return models.ErrNotAssigned
+		}
+
+		review, ok := stored.reviews[reviewerID]
+		if !ok {
+			return models.ErrNotAssigned
+		}
+
+		review.State = state
+		review.DecidedAt = memNow()
+		stored.reviews[reviewerID] = review
+		return nil
+	})
+}
+
+func (r *memPRRepo) SetStatus(ctx context.Context, prID string, status string) error {
+	return r.access(func(st *memState) error {
+		stored, ok := st.prs[prID]
+		if !ok {
+			return nil
+		}
+
+		stored.pr.Status = status
+		stored.pr.ClosedAt = nil
+		if status == models.PRStatusClosed {
+			stored.pr.ClosedAt = memNow()
+		}
+		return nil
+	})
+}
+
+func (r *memPRRepo) RemoveAllReviewers(ctx context.Context, prID string) error {
+	return r.access(func(st *memState) error {
+		if stored, ok := st.prs[prID]; ok {
+			stored.reviews = make(map[string]models.Review)
+		}
+		return nil
+	})
+}
+
+type memAuditRepo struct {
+	access memAccess
+}
+
+func (r *memAuditRepo) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.prs[entry.PullRequestID]; !ok {
+			return models.ErrPRNotFound
+		}
+		st.audit = append(st.audit, *entry)
+		return nil
+	})
+}
+
+type memIdentityRepo struct {
+	access memAccess
+}
+
+func (r *memIdentityRepo) SetMapping(ctx context.Context, mapping *models.IdentityMapping) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.users[mapping.UserID]; !ok {
+			return models.ErrUserNotFound
+		}
+		st.identities[memIdentityKey{provider: mapping.Provider, login: mapping.Login}] = mapping.UserID
+		return nil
+	})
+}
+
+func (r *memIdentityRepo) ResolveUserID(ctx context.Context, provider string, login string) (string, error) {
+	var userID string
+	err := r.access(func(st *memState) error {
+		var ok bool
+		userID, ok = st.identities[memIdentityKey{provider: provider, login: login}]
+		if !ok {
+			return models.ErrIdentityNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+type memSubscriptionRepo struct {
+	access memAccess
+}
+
+func copySubscription(sub models.WebhookSubscription) models.WebhookSubscription {
+	sub.Events = append([]string{}, sub.Events...)
+	return sub
+}
+
+func (r *memSubscriptionRepo) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	return r.access(func(st *memState) error {
+		if _, exists := st.subs[sub.ID]; exists {
+			return fmt.Errorf("subscription %s already exists", sub.ID)
+		}
+
+		sub.CreatedAt = memNow()
+		st.subs[sub.ID] = copySubscription(*sub)
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := r.access(func(st *memState) error {
+		stored, ok := st.subs[id]
+		if !ok {
+			return models.ErrSubscriptionNotFound
+		}
+		sub = copySubscription(stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (r *memSubscriptionRepo) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs, err := r.list(func(sub models.WebhookSubscription) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(*subs[j].CreatedAt)
+	})
+	return subs, nil
+}
+
+func (r *memSubscriptionRepo) ListActiveForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	return r.list(func(sub models.WebhookSubscription) bool {
+		if !sub.IsActive {
+			return false
+		}
+		if len(sub.Events) == 0 {
+			return true
+		}
+		for _, e := range sub.Events {
+			if e == eventType {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// list returns matching subscriptions ordered by id.
+func (r *memSubscriptionRepo) list(match func(sub models.WebhookSubscription) bool) ([]models.WebhookSubscription, error) {
+	subs := []models.WebhookSubscription{}
+	err := r.access(func(st *memState) error {
+		for _, sub := range st.subs {
+			if match(sub) {
+				subs = append(subs, copySubscription(sub))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, nil
+}
+
+func (r *memSubscriptionRepo) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	return r.access(func(st *memState) error {
+		stored, ok := st.subs[sub.ID]
+		if !ok {
+			return models.ErrSubscriptionNotFound
+		}
+
+		stored.URL = sub.URL
+		stored.Secret = sub.Secret
+		stored.Events = append([]string{}, sub.Events...)
+		stored.IsActive = sub.IsActive
+		st.subs[sub.ID] = stored
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) DeleteSubscription(ctx context.Context, id string) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.subs[id]; !ok {
+			return models.ErrSubscriptionNotFound
+		}
+		delete(st.subs, id)
+
+		kept := st.deliveries[:0]
+		for _, d := range st.deliveries {
+			if d.SubscriptionID != id {
+				kept = append(kept, d)
+			}
+		}
+		st.deliveries = kept
+
+		retries := st.retries[:0]
+		for _, row := range st.retries {
+			if row.subscriptionID != id {
+				retries = append(retries, row)
+			}
+		}
+		st.retries = retries
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.subs[delivery.SubscriptionID]; !ok {
+			return models.ErrSubscriptionNotFound
+		}
+		st.deliveries = append(st.deliveries, *delivery)
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	return r.access(func(st *memState) error {
+		if _, ok := st.subs[retry.Subscription.ID]; !ok {
+			return models.ErrSubscriptionNotFound
+		}
+
+		st.retrySeq++
+		retry.ID = st.retrySeq
+		e := retry.Event
+		e.Data = append([]byte(nil), retry.Event.Data...)
+		st.retries = append(st.retries, &memRetryRow{
+			id:             retry.ID,
+			subscriptionID: retry.Subscription.ID,
+			event:          e,
+			attempt:        retry.Attempt,
+			nextAttemptAt:  retry.NextAttemptAt,
+		})
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) ClaimRetries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookRetry, error) {
+	retries := []models.WebhookRetry{}
+	err := r.access(func(st *memState) error {
+		now := time.Now().UTC()
+		lockedUntil := now.Add(lease)
+
+		due := []*memRetryRow{}
+		for _, row := range st.retries {
+			if row.nextAttemptAt.After(now) || (row.lockedUntil != nil && !row.lockedUntil.Before(now)) {
+				continue
+			}
+			if sub, ok := st.subs[row.subscriptionID]; ok && sub.IsActive {
+				due = append(due, row)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if !due[i].nextAttemptAt.Equal(due[j].nextAttemptAt) {
+				return due[i].nextAttemptAt.Before(due[j].nextAttemptAt)
+			}
+			return due[i].id < due[j].id
+		})
+
+		for _, row := range due[:min(limit, len(due))] {
+			row.lockedUntil = &lockedUntil
+
+			e := row.event
+			e.Data = append([]byte(nil), row.event.Data...)
+			retries = append(retries, models.WebhookRetry{
+				ID:            row.id,
+				Subscription:  copySubscription(st.subs[row.subscriptionID]),
+				Event:         e,
+				Attempt:       row.attempt,
+				NextAttemptAt: row.nextAttemptAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return retries, nil
+}
+
+func (r *memSubscriptionRepo) RescheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	return r.access(func(st *memState) error {
+		for _, row := range st.retries {
+			if row.id == retry.ID {
+				row.attempt = retry.Attempt
+				row.nextAttemptAt = retry.NextAttemptAt
+				row.lockedUntil = nil
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memSubscriptionRepo) DeleteRetry(ctx context.Context, id int64) error {
+	return r.access(func(st *memState) error {
+		kept := st.retries[:0]
+		for _, row := range st.retries {
+			if row.id != id {
+				kept = append(kept, row)
+			}
+		}
+		st.retries = kept
+		return nil
+	})
+}
+
+type memOutboxRepo struct {
+	access memAccess
+}
+
+func (r *memOutboxRepo) Enqueue(ctx context.Context, event *models.Event) error {
+	return r.access(func(st *memState) error {
+		for _, row := range st.outbox {
+			if row.event.ID == event.ID {
+				return fmt.Errorf("event %s is already in the outbox", event.ID)
+			}
+		}
+
+		st.outboxSeq++
+		e := *event
+		e.Data = append([]byte(nil), event.Data...)
+		st.outbox = append(st.outbox, &memOutboxRow{
+			id:        st.outboxSeq,
+			event:     e,
+			createdAt: time.Now().UTC(),
+		})
+		return nil
+	})
+}
+
+func (r *memOutboxRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error) {
+	events := []models.Event{}
+	err := r.access(func(st *memState) error {
+		now := time.Now().UTC()
+		lockedUntil := now.Add(lease)
+
+		for _, row := range st.outbox {
+			if len(events) >= limit {
+				break
+			}
+			if row.dispatchedAt != nil || (row.lockedUntil != nil && !row.lockedUntil.Before(now)) {
+				continue
+			}
+
+			row.lockedUntil = &lockedUntil
+			row.attempts++
+
+			e := row.event
+			e.Data = append([]byte(nil), row.event.Data...)
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *memOutboxRepo) MarkDispatched(ctx context.Context, eventID string) error {
+	return r.access(func(st *memState) error {
+		for _, row := range st.outbox {
+			if row.event.ID == eventID {
+				row.dispatchedAt = memNow()
+				row.lockedUntil = nil
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memOutboxRepo) Stats(ctx context.Context) (*models.OutboxStats, error) {
+	stats := &models.OutboxStats{}
+	err := r.access(func(st *memState) error {
+		var oldest *time.Time
+		for _, row := range st.outbox {
+			if row.dispatchedAt != nil {
+				continue
+			}
+			stats.Pending++
+			if oldest == nil || row.createdAt.Before(*oldest) {
+				oldest = &row.createdAt
+			}
+		}
+
+		if oldest != nil {
+			stats.LagSeconds = time.Since(*oldest).Seconds()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "testing"
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) backend {
+		store := NewMemoryStore()
+		return backend{repos: store.Repos(), tx: store}
+	})
+}
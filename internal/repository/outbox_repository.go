@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository struct {
+	db DBTX
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: pool}
+}
+
+func (r *OutboxRepository) Enqueue(ctx context.Context, event *models.Event) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO outbox (event_id, event_type, payload, occurred_at) VALUES ($1, $2, $3, $4)",
+		event.ID, event.Type, event.Data, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
+
+// Claim leases up to limit undispatched events in insertion order. A leased
+// event is invisible to other claimers until the lease expires, so an event
+// whose dispatcher crashed is picked up again (at-least-once delivery).
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE outbox SET locked_until = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond', attempts = attempts + 1
+		 WHERE id IN (
+			SELECT id FROM outbox
+			WHERE dispatched_at IS NULL AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING id, event_id, event_type, payload, occurred_at`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	type claimed struct {
+		id    int64
+		event models.Event
+	}
+
+	var batch []claimed
+	for rows.Next() {
+		var c claimed
+		if err := rows.Scan(&c.id, &c.event.ID, &c.event.Type, &c.event.Data, &c.event.OccurredAt); err != nil {
+			return nil, err
+		}
+		batch = append(batch, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING does not keep the subquery order.
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].id < batch[j].id
+	})
+
+	events := make([]models.Event, 0, len(batch))
+	for _, c := range batch {
+		events = append(events, c.event)
+	}
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, eventID string) error {
+	_, err := r.db.Exec(ctx,
+		"UPDATE outbox SET dispatched_at = CURRENT_TIMESTAMP, locked_until = NULL WHERE event_id = $1",
+		eventID)
+	if err != nil {
+		return fmt.Errorf("failed to mark event %s dispatched: %w", eventID, err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) Stats(ctx context.Context) (*models.OutboxStats, error) {
+	stats := &models.OutboxStats{}
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MIN(created_at)), 0)::float8
+		 FROM outbox WHERE dispatched_at IS NULL`).Scan(&stats.Pending, &stats.LagSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"pr-reviewer-service/internal/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestPostgresConformance runs the conformance suite against the database in
+// TEST_DATABASE_URL. All tables are truncated before every subtest, so never
+// point it at a database with data you care about.
+func TestPostgresConformance(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	runConformance(t, func(t *testing.T) backend {
+		_, err := pool.Exec(ctx, `
+			TRUNCATE outbox, webhook_retries, webhook_deliveries, webhook_subscriptions, identity_mappings,
+				pr_audit_log, pr_reviewers, pull_requests, team_memberships, users, teams
+			RESTART IDENTITY CASCADE
+		`)
+		if err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return backend{repos: NewRepos(pool), tx: NewStore(pool)}
+	})
+}
@@ -12,72 +12,98 @@ import (
 )
 
 type PRRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
 func NewPRRepository(pool *pgxpool.Pool) *PRRepository {
-	return &PRRepository{pool: pool}
+	return &PRRepository{db: pool}
 }
 
 func (r *PRRepository) CreatePR(ctx context.Context, pr *models.PullRequest) error {
 	var exists bool
-	err := r.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", pr.ID).Scan(&exists)
+	err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", pr.ID).Scan(&exists)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to check PR %s: %w", pr.ID, err)
 	}
 
 	if exists {
-		return fmt.Errorf("PR already exists")
+		return models.ErrPRExists
 	}
 
-	_, err = r.pool.Exec(ctx,
-		"INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status) VALUES ($1, $2, $3, $4)",
-		pr.ID, pr.Name, pr.AuthorID, "OPEN")
-	
-	return err
+	_, err = r.db.Exec(ctx,
+		"INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_name, status) VALUES ($1, $2, $3, NULLIF($4, ''), $5)",
+		pr.ID, pr.Name, pr.AuthorID, pr.TeamName, pr.Status)
+
+	switch {
+	case err == nil:
+		return nil
+	case isUniqueViolation(err):
+		return models.ErrPRExists
+	case isForeignKeyViolation(err) && violatedConstraint(err) == "pull_requests_team_name_fkey":
+		return models.ErrTeamNotFound
+	case isForeignKeyViolation(err):
+		return models.ErrUserNotFound
+	}
+	return fmt.Errorf("failed to create PR %s: %w", pr.ID, err)
 }
 
 func (r *PRRepository) AssignReviewers(ctx context.Context, prID string, reviewerIDs []string) error {
 	for _, reviewerID := range reviewerIDs {
-		_, err := r.pool.Exec(ctx,
+		_, err := r.db.Exec(ctx,
 			"INSERT INTO pr_reviewers (pull_request_id, reviewer_id) VALUES ($1, $2)",
 			prID, reviewerID)
+		if isForeignKeyViolation(err) {
+			return models.ErrNotFound.WithMessage("PR or reviewer not found")
+		}
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to assign %s to PR %s: %w", reviewerID, prID, err)
 		}
 	}
 	return nil
 }
 
 func (r *PRRepository) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return r.getPR(ctx, prID, "")
+}
+
+// GetPRForUpdate is GetPR that also locks the PR row until the end of the
+// transaction, so concurrent mutations of the same PR run one after another.
+// Outside of a transaction the lock is released immediately.
+func (r *PRRepository) GetPRForUpdate(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return r.getPR(ctx, prID, " FOR UPDATE")
+}
+
+func (r *PRRepository) getPR(ctx context.Context, prID string, lock string) (*models.PullRequest, error) {
 	pr := &models.PullRequest{}
 
-	err := r.pool.QueryRow(ctx,
-		"SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at FROM pull_requests WHERE pull_request_id = $1",
-		prID).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt)
+	err := r.db.QueryRow(ctx,
+		"SELECT pull_request_id, pull_request_name, author_id, COALESCE(team_name, ''), status, created_at, merged_at, closed_at FROM pull_requests WHERE pull_request_id = $1"+lock,
+		prID).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.TeamName, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.ClosedAt)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("PR not found")
+			return nil, models.ErrPRNotFound
 		}
-		return nil, err
+		return nil, fmt.Errorf("failed to get PR %s: %w", prID, err)
 	}
 
-	rows, err := r.pool.Query(ctx,
-		"SELECT reviewer_id FROM pr_reviewers WHERE pull_request_id = $1 ORDER BY reviewer_id",
+	rows, err := r.db.Query(ctx,
+		"SELECT reviewer_id, review_state, assigned_at, decided_at FROM pr_reviewers WHERE pull_request_id = $1 ORDER BY reviewer_id",
 		prID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get reviewers of PR %s: %w", prID, err)
 	}
 	defer rows.Close()
 
 	pr.AssignedReviewers = []string{}
+	pr.Reviews = []models.Review{}
 	for rows.Next() {
-		var reviewerID string
-		if err := rows.Scan(&reviewerID); err != nil {
+		var review models.Review
+		if err := rows.Scan(&review.ReviewerID, &review.State, &review.AssignedAt, &review.DecidedAt); err != nil {
 			return nil, err
 		}
-		pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+		pr.AssignedReviewers = append(pr.AssignedReviewers, review.ReviewerID)
+		pr.Reviews = append(pr.Reviews, review)
 	}
 
 	return pr, rows.Err()
@@ -89,23 +115,23 @@ func (r *PRRepository) MergePR(ctx context.Context, prID string) (*models.PullRe
 		return nil, err
 	}
 
-	if pr.Status == "MERGED" {
+	if pr.Status == models.PRStatusMerged {
 		return pr, nil
 	}
 
-	_, err = r.pool.Exec(ctx,
+	_, err = r.db.Exec(ctx,
 		"UPDATE pull_requests SET status = 'MERGED', merged_at = CURRENT_TIMESTAMP WHERE pull_request_id = $1",
 		prID)
 
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to merge PR %s: %w", prID, err)
 	}
 
 	return r.GetPR(ctx, prID)
 }
 
 func (r *PRRepository) GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
-	rows, err := r.pool.Query(ctx,
+	rows, err := r.db.Query(ctx,
 		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status 
 		 FROM pull_requests pr
 		 INNER JOIN pr_reviewers rev ON pr.pull_request_id = rev.pull_request_id
@@ -113,7 +139,7 @@ func (r *PRRepository) GetUserReviews(ctx context.Context, userID string) ([]mod
 		 ORDER BY pr.pull_request_id`,
 		userID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get reviews of %s: %w", userID, err)
 	}
 	defer rows.Close()
 
@@ -130,16 +156,86 @@ func (r *PRRepository) GetUserReviews(ctx context.Context, userID string) ([]mod
 }
 
 func (r *PRRepository) RemoveReviewer(ctx context.Context, prID string, reviewerID string) error {
-	_, err := r.pool.Exec(ctx,
+	_, err := r.db.Exec(ctx,
 		"DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND reviewer_id = $2",
 		prID, reviewerID)
-	return err
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from PR %s: %w", reviewerID, prID, err)
+	}
+	return nil
 }
 
 func (r *PRRepository) IsReviewerAssigned(ctx context.Context, prID string, reviewerID string) (bool, error) {
 	var exists bool
-	err := r.pool.QueryRow(ctx,
+	err := r.db.QueryRow(ctx,
 		"SELECT EXISTS(SELECT 1 FROM pr_reviewers WHERE pull_request_id = $1 AND reviewer_id = $2)",
 		prID, reviewerID).Scan(&exists)
-	return exists, err
-}
\ No newline at end of file
+	if err != nil {
+		return false, fmt.Errorf("failed to check reviewer %s of PR %s: %w", reviewerID, prID, err)
+	}
+	return exists, nil
+}
+
+func (r *PRRepository) CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(userIDs))
+	for _, id := range userIDs {
+		counts[id] = 0
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT rev.reviewer_id, COUNT(*)
+		 FROM pr_reviewers rev
+		 INNER JOIN pull_requests pr ON pr.pull_request_id = rev.pull_request_id
+		 WHERE pr.status = 'OPEN' AND ($1::varchar[] IS NULL OR rev.reviewer_id = ANY($1))
+		 GROUP BY rev.reviewer_id`,
+		userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open reviews: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reviewerID string
+		var count int
+		if err := rows.Scan(&reviewerID, &count); err != nil {
+			return nil, err
+		}
+		counts[reviewerID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *PRRepository) SetReviewState(ctx context.Context, prID string, reviewerID string, state string) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE pr_reviewers SET review_state = $3, decided_at = CURRENT_TIMESTAMP WHERE pull_request_id = $1 AND reviewer_id = $2",
+		prID, reviewerID, state)
+	if err != nil {
+		return fmt.Errorf("failed to set review state of %s on PR %s: %w", reviewerID, prID, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotAssigned
+	}
+	return nil
+}
+
+func (r *PRRepository) SetStatus(ctx context.Context, prID string, status string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE pull_requests
+		 SET status = $2, closed_at = CASE WHEN $2 = 'CLOSED' THEN CURRENT_TIMESTAMP ELSE NULL END
+		 WHERE pull_request_id = $1`,
+		prID, status)
+	if err != nil {
+		return fmt.Errorf("failed to set status of PR %s: %w", prID, err)
+	}
+	return nil
+}
+
+func (r *PRRepository) RemoveAllReviewers(ctx context.Context, prID string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM pr_reviewers WHERE pull_request_id = $1", prID)
+	if err != nil {
+		return fmt.Errorf("failed to remove reviewers of PR %s: %w", prID, err)
+	}
+	return nil
+}
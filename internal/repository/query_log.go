@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"pr-reviewer-service/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+// NewQueryLogger returns a pgx tracer that logs every statement with the
+// logger carried by the query context, so SQL appears under the request ID
+// that issued it. Everything is logged at debug level, failures included:
+// the repositories translate expected failures such as unique violations into
+// domain errors, and unexpected ones are logged by the HTTP layer. Arguments
+// are never logged because they include webhook secrets.
+func NewQueryLogger() pgx.QueryTracer {
+	return &tracelog.TraceLog{
+		Logger:   tracelog.LoggerFunc(logQuery),
+		LogLevel: tracelog.LogLevelInfo,
+	}
+}
+
+func logQuery(ctx context.Context, _ tracelog.LogLevel, msg string, data map[string]any) {
+	logger := logging.FromContext(ctx)
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(data))
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		if key == "args" {
+			continue
+		}
+		attrs = append(attrs, slog.Any(key, data[key]))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "sql "+msg, attrs...)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+)
+
+// The interfaces below are what services depend on. PostgreSQL
+// implementations live next to them (*PRRepository etc.), an in-memory
+// implementation with the same semantics is returned by NewMemoryStore.
+
+type PRRepo interface {
+	CreatePR(ctx context.Context, pr *models.PullRequest) error
+	AssignReviewers(ctx context.Context, prID string, reviewerIDs []string) error
+	GetPR(ctx context.Context, prID string) (*models.PullRequest, error)
+	GetPRForUpdate(ctx context.Context, prID string) (*models.PullRequest, error)
+	MergePR(ctx context.Context, prID string) (*models.PullRequest, error)
+	GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error)
+	RemoveReviewer(ctx context.Context, prID string, reviewerID string) error
+	IsReviewerAssigned(ctx context.Context, prID string, reviewerID string) (bool, error)
+	// CountOpenReviews returns the number of OPEN PRs each of userIDs reviews.
+	// A nil userIDs counts every reviewer with at least one open review.
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+	SetReviewState(ctx context.Context, prID string, reviewerID string, state string) error
+	SetStatus(ctx context.Context, prID string, status string) error
+	RemoveAllReviewers(ctx context.Context, prID string) error
+}
+
+type UserRepo interface {
+	// CreateOrUpdateUser saves the name and the global active flag. The
+	// teams of the user are saved through MembershipRepo.
+	CreateOrUpdateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	// GetTeamMembers returns the members of teamName with TeamName set to it.
+	// A member is active only if both the user and the membership are.
+	GetTeamMembers(ctx context.Context, teamName string) ([]models.User, error)
+	SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error)
+}
+
+type MembershipRepo interface {
+	// SaveMembership adds the user to the team or updates the role and the
+	// active flag of their membership.
+	SaveMembership(ctx context.Context, membership *models.TeamMembership) error
+	GetMembership(ctx context.Context, teamName string, userID string) (*models.TeamMembership, error)
+	ListTeamMemberships(ctx context.Context, teamName string) ([]models.TeamMembership, error)
+	DeleteMembership(ctx context.Context, teamName string, userID string) error
+}
+
+type TeamRepo interface {
+	CreateTeam(ctx context.Context, team *models.Team) error
+	GetTeam(ctx context.Context, teamName string) (*models.Team, error)
+	GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error)
+	GetSettingsForUpdate(ctx context.Context, teamName string) (*models.TeamSettings, error)
+	UpdateSettings(ctx context.Context, settings *models.TeamSettings) error
+	// GetRoundRobinCursorForUpdate returns the last reviewer the round_robin
+	// strategy handed out in the team, "" if none, and locks it until the
+	// transaction ends.
+	GetRoundRobinCursorForUpdate(ctx context.Context, teamName string) (string, error)
+	SetRoundRobinCursor(ctx context.Context, teamName string, userID string) error
+	// RenameTeam renames the team together with its memberships and PRs.
+	RenameTeam(ctx context.Context, oldName string, newName string) error
+	ArchiveTeam(ctx context.Context, teamName string) error
+	// DeleteTeam fails with ErrTeamNotEmpty while the team has members.
+	// PRs of a deleted team are kept without a team.
+	DeleteTeam(ctx context.Context, teamName string) error
+}
+
+type AuditRepo interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+}
+
+type IdentityRepo interface {
+	SetMapping(ctx context.Context, mapping *models.IdentityMapping) error
+	ResolveUserID(ctx context.Context, provider string, login string) (string, error)
+}
+
+type SubscriptionRepo interface {
+	CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	ListActiveForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id string) error
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ScheduleRetry stores a new retry and sets its ID.
+	ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error
+	// ClaimRetries leases up to limit due retries of active subscriptions,
+	// oldest first, the same way OutboxRepo.Claim leases events.
+	ClaimRetries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookRetry, error)
+	// RescheduleRetry stores the new Attempt and NextAttemptAt of a claimed
+	// retry and releases its lease.
+	RescheduleRetry(ctx context.Context, retry *models.WebhookRetry) error
+	DeleteRetry(ctx context.Context, id int64) error
+}
+
+type OutboxRepo interface {
+	Enqueue(ctx context.Context, event *models.Event) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error)
+	MarkDispatched(ctx context.Context, eventID string) error
+	Stats(ctx context.Context) (*models.OutboxStats, error)
+}
+
+// Transactor runs fn with repositories bound to a single transaction. Every
+// service operation that reads and then writes, or writes more than once,
+// goes through WithTx so it either fully happens or not at all.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(tx *Repos) error) error
+}
+
+var (
+	_ PRRepo           = (*PRRepository)(nil)
+	_ UserRepo         = (*UserRepository)(nil)
+	_ MembershipRepo   = (*MembershipRepository)(nil)
+	_ TeamRepo         = (*TeamRepository)(nil)
+	_ AuditRepo        = (*AuditRepository)(nil)
+	_ IdentityRepo     = (*IdentityRepository)(nil)
+	_ SubscriptionRepo = (*SubscriptionRepository)(nil)
+	_ OutboxRepo       = (*OutboxRepository)(nil)
+	_ Transactor       = (*Store)(nil)
+	_ Transactor       = (*MemoryStore)(nil)
+)
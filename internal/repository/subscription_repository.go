@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SubscriptionRepository struct {
+	db DBTX
+}
+
+func NewSubscriptionRepository(pool *pgxpool.Pool) *SubscriptionRepository {
+	return &SubscriptionRepository{db: pool}
+}
+
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO webhook_subscriptions (subscription_id, url, secret, events, is_active)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING created_at`,
+		sub.ID, sub.URL, sub.Secret, sub.Events, sub.IsActive).Scan(&sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription %s: %w", sub.ID, err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{}
+	err := r.db.QueryRow(ctx,
+		"SELECT subscription_id, url, secret, events, is_active, created_at FROM webhook_subscriptions WHERE subscription_id = $1",
+		id).Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.Events, &sub.IsActive, &sub.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("failed to get subscription %s: %w", id, err)
+	}
+
+	return sub, nil
+}
+
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return r.list(ctx,
+		"SELECT subscription_id, url, secret, events, is_active, created_at FROM webhook_subscriptions ORDER BY created_at, subscription_id")
+}
+
+// ListActiveForEvent returns active subscriptions that either listen to
+// eventType explicitly or have an empty events list (all events).
+func (r *SubscriptionRepository) ListActiveForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	return r.list(ctx,
+		`SELECT subscription_id, url, secret, events, is_active, created_at
+		 FROM webhook_subscriptions
+		 WHERE is_active AND (cardinality(events) = 0 OR $1 = ANY(events))
+		 ORDER BY subscription_id`,
+		eventType)
+}
+
+func (r *SubscriptionRepository) list(ctx context.Context, query string, args ...any) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.Events, &sub.IsActive, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *SubscriptionRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE webhook_subscriptions SET url = $2, secret = $3, events = $4, is_active = $5 WHERE subscription_id = $1",
+		sub.ID, sub.URL, sub.Secret, sub.Events, sub.IsActive)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription %s: %w", sub.ID, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscription(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM webhook_subscriptions WHERE subscription_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription %s: %w", id, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, attempt, status_code, error, delivered)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, ''), $7)`,
+		delivery.SubscriptionID, delivery.EventID, delivery.EventType, delivery.Attempt,
+		delivery.StatusCode, delivery.Error, delivery.Delivered)
+	if isForeignKeyViolation(err) {
+		return models.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record delivery of %s to %s: %w", delivery.EventID, delivery.SubscriptionID, err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO webhook_retries (subscription_id, event_id, event_type, payload, occurred_at, attempt, next_attempt_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id`,
+		retry.Subscription.ID, retry.Event.ID, retry.Event.Type, retry.Event.Data, retry.Event.OccurredAt,
+		retry.Attempt, retry.NextAttemptAt).Scan(&retry.ID)
+	if isForeignKeyViolation(err) {
+		return models.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to schedule retry of %s to %s: %w", retry.Event.ID, retry.Subscription.ID, err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) ClaimRetries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookRetry, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE webhook_retries r SET locked_until = now() + $2 * INTERVAL '1 millisecond'
+		 FROM webhook_subscriptions s
+		 WHERE s.subscription_id = r.subscription_id AND r.id IN (
+			SELECT wr.id FROM webhook_retries wr
+			JOIN webhook_subscriptions ws ON ws.subscription_id = wr.subscription_id
+			WHERE ws.is_active AND wr.next_attempt_at <= now()
+			  AND (wr.locked_until IS NULL OR wr.locked_until < now())
+			ORDER BY wr.next_attempt_at, wr.id
+			LIMIT $1
+			FOR UPDATE OF wr SKIP LOCKED
+		 )
+		 RETURNING r.id, s.subscription_id, s.url, s.secret, s.events, s.is_active, s.created_at,
+			r.event_id, r.event_type, r.payload, r.occurred_at, r.attempt, r.next_attempt_at`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook retries: %w", err)
+	}
+	defer rows.Close()
+
+	retries := []models.WebhookRetry{}
+	for rows.Next() {
+		var retry models.WebhookRetry
+		sub := &retry.Subscription
+		if err := rows.Scan(&retry.ID, &sub.ID, &sub.URL, &sub.Secret, &sub.Events, &sub.IsActive, &sub.CreatedAt,
+			&retry.Event.ID, &retry.Event.Type, &retry.Event.Data, &retry.Event.OccurredAt,
+			&retry.Attempt, &retry.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		retries = append(retries, retry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING does not keep the subquery order.
+	sort.Slice(retries, func(i, j int) bool {
+		if !retries[i].NextAttemptAt.Equal(retries[j].NextAttemptAt) {
+			return retries[i].NextAttemptAt.Before(retries[j].NextAttemptAt)
+		}
+		return retries[i].ID < retries[j].ID
+	})
+	return retries, nil
+}
+
+func (r *SubscriptionRepository) RescheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	_, err := r.db.Exec(ctx,
+		"UPDATE webhook_retries SET attempt = $2, next_attempt_at = $3, locked_until = NULL WHERE id = $1",
+		retry.ID, retry.Attempt, retry.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule retry %d: %w", retry.ID, err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteRetry(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM webhook_retries WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete retry %d: %w", id, err)
+	}
+	return nil
+}
@@ -7,63 +7,187 @@ import (
 
 	"pr-reviewer-service/internal/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type TeamRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
 func NewTeamRepository(pool *pgxpool.Pool) *TeamRepository {
-	return &TeamRepository{pool: pool}
+	return &TeamRepository{db: pool}
 }
 
 func (r *TeamRepository) CreateTeam(ctx context.Context, team *models.Team) error {
 	var exists bool
-	err := r.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", team.TeamName).Scan(&exists)
+	err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", team.TeamName).Scan(&exists)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to check team %s: %w", team.TeamName, err)
 	}
 
 	if exists {
-		return fmt.Errorf("team already exists")
+		return models.ErrTeamExists
 	}
 
-	_, err = r.pool.Exec(ctx, "INSERT INTO teams (team_name) VALUES ($1)", team.TeamName)
-	return err
+	_, err = r.db.Exec(ctx, "INSERT INTO teams (team_name, reviewer_strategy) VALUES ($1, NULLIF($2, ''))", team.TeamName, team.ReviewerStrategy)
+	if isUniqueViolation(err) {
+		return models.ErrTeamExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create team %s: %w", team.TeamName, err)
+	}
+	return nil
 }
 
 func (r *TeamRepository) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
-	var exists bool
-	err := r.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", teamName).Scan(&exists)
+	settings, err := r.GetSettings(ctx, teamName)
 	if err != nil {
 		return nil, err
 	}
 
-	if !exists {
-		return nil, errors.New("team not found")
-	}
-
-	rows, err := r.pool.Query(ctx, 
-		"SELECT user_id, username, is_active FROM users WHERE team_name = $1 ORDER BY user_id", 
+	rows, err := r.db.Query(ctx,
+		`SELECT u.user_id, u.username, u.is_active AND m.is_active, m.role
+		 FROM team_memberships m
+		 INNER JOIN users u ON u.user_id = m.user_id
+		 WHERE m.team_name = $1
+		 ORDER BY u.user_id`,
 		teamName)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get members of team %s: %w", teamName, err)
 	}
 	defer rows.Close()
 
 	team := &models.Team{
-		TeamName: teamName,
-		Members:  []models.TeamMember{},
+		TeamName:         teamName,
+		Members:          []models.TeamMember{},
+		ReviewerStrategy: settings.ReviewerStrategy,
+		Archived:         settings.Archived,
 	}
 
 	for rows.Next() {
 		var member models.TeamMember
-		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive); err != nil {
+		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive, &member.Role); err != nil {
 			return nil, err
 		}
 		team.Members = append(team.Members, member)
 	}
 
 	return team, rows.Err()
-}
\ No newline at end of file
+}
+
+func (r *TeamRepository) GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	return r.getSettings(ctx, teamName, "")
+}
+
+func (r *TeamRepository) GetSettingsForUpdate(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	return r.getSettings(ctx, teamName, " FOR UPDATE")
+}
+
+func (r *TeamRepository) getSettings(ctx context.Context, teamName string, lock string) (*models.TeamSettings, error) {
+	settings := &models.TeamSettings{TeamName: teamName}
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(reviewer_strategy, ''), reviewers_per_pr, min_reviewers, allow_fewer_than_required,
+		        archived_at IS NOT NULL
+		 FROM teams WHERE team_name = $1`+lock,
+		teamName).Scan(&settings.ReviewerStrategy, &settings.ReviewersPerPR, &settings.MinReviewers, &settings.AllowFewerThanRequired,
+		&settings.Archived)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("failed to get settings of team %s: %w", teamName, err)
+	}
+
+	return settings, nil
+}
+
+func (r *TeamRepository) UpdateSettings(ctx context.Context, settings *models.TeamSettings) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE teams
+		 SET reviewer_strategy = NULLIF($2, ''), reviewers_per_pr = $3, min_reviewers = $4, allow_fewer_than_required = $5
+		 WHERE team_name = $1`,
+		settings.TeamName, settings.ReviewerStrategy, settings.ReviewersPerPR, settings.MinReviewers, settings.AllowFewerThanRequired)
+	if err != nil {
+		return fmt.Errorf("failed to update settings of team %s: %w", settings.TeamName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *TeamRepository) GetRoundRobinCursorForUpdate(ctx context.Context, teamName string) (string, error) {
+	var userID string
+	err := r.db.QueryRow(ctx,
+		"SELECT COALESCE(round_robin_last, '') FROM teams WHERE team_name = $1 FOR UPDATE",
+		teamName).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrTeamNotFound
+		}
+		return "", fmt.Errorf("failed to get round robin cursor of team %s: %w", teamName, err)
+	}
+
+	return userID, nil
+}
+
+func (r *TeamRepository) SetRoundRobinCursor(ctx context.Context, teamName string, userID string) error {
+	tag, err := r.db.Exec(ctx, "UPDATE teams SET round_robin_last = $2 WHERE team_name = $1", teamName, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set round robin cursor of team %s: %w", teamName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *TeamRepository) RenameTeam(ctx context.Context, oldName string, newName string) error {
+	// team_memberships and pull_requests follow through ON UPDATE CASCADE.
+	tag, err := r.db.Exec(ctx, "UPDATE teams SET team_name = $2 WHERE team_name = $1", oldName, newName)
+	if isUniqueViolation(err) {
+		return models.ErrTeamExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename team %s to %s: %w", oldName, newName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *TeamRepository) ArchiveTeam(ctx context.Context, teamName string) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE teams SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP) WHERE team_name = $1",
+		teamName)
+	if err != nil {
+		return fmt.Errorf("failed to archive team %s: %w", teamName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *TeamRepository) DeleteTeam(ctx context.Context, teamName string) error {
+	// Memberships block the delete, pull_requests.team_name is set to NULL.
+	tag, err := r.db.Exec(ctx, "DELETE FROM teams WHERE team_name = $1", teamName)
+	if isForeignKeyViolation(err) {
+		return models.ErrTeamNotEmpty
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete team %s: %w", teamName, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrTeamNotFound
+	}
+	return nil
+}
@@ -3,53 +3,65 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"pr-reviewer-service/internal/models"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
 func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
-	return &UserRepository{pool: pool}
+	return &UserRepository{db: pool}
 }
 
+// defaultTeamSQL selects the team the user u joined first.
+const defaultTeamSQL = `COALESCE((SELECT m.team_name FROM team_memberships m
+	WHERE m.user_id = u.user_id ORDER BY m.joined_at, m.team_name LIMIT 1), '')`
+
 func (r *UserRepository) CreateOrUpdateUser(ctx context.Context, user *models.User) error {
-	_, err := r.pool.Exec(ctx,
-		`INSERT INTO users (user_id, username, team_name, is_active) 
-		 VALUES ($1, $2, $3, $4)
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO users (user_id, username, is_active) 
+		 VALUES ($1, $2, $3)
 		 ON CONFLICT (user_id) DO UPDATE 
-		 SET username = $2, team_name = $3, is_active = $4`,
-		user.UserID, user.Username, user.TeamName, user.IsActive)
-	return err
+		 SET username = $2, is_active = $3`,
+		user.UserID, user.Username, user.IsActive)
+	if err != nil {
+		return fmt.Errorf("failed to save user %s: %w", user.UserID, err)
+	}
+	return nil
 }
 
 func (r *UserRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
-	err := r.pool.QueryRow(ctx,
-		"SELECT user_id, username, team_name, is_active FROM users WHERE user_id = $1",
+	err := r.db.QueryRow(ctx,
+		"SELECT u.user_id, u.username, "+defaultTeamSQL+", u.is_active FROM users u WHERE u.user_id = $1",
 		userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserNotFound
 		}
-		return nil, err
+		return nil, fmt.Errorf("failed to get user %s: %w", userID, err)
 	}
 
 	return &user, nil
 }
 
 func (r *UserRepository) GetTeamMembers(ctx context.Context, teamName string) ([]models.User, error) {
-	rows, err := r.pool.Query(ctx,
-		"SELECT user_id, username, team_name, is_active FROM users WHERE team_name = $1 ORDER BY user_id",
+	rows, err := r.db.Query(ctx,
+		`SELECT u.user_id, u.username, m.team_name, u.is_active AND m.is_active
+		 FROM team_memberships m
+		 INNER JOIN users u ON u.user_id = m.user_id
+		 WHERE m.team_name = $1
+		 ORDER BY u.user_id`,
 		teamName)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get members of team %s: %w", teamName, err)
 	}
 	defer rows.Close()
 
@@ -67,16 +79,16 @@ func (r *UserRepository) GetTeamMembers(ctx context.Context, teamName string) ([
 
 func (r *UserRepository) SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error) {
 	user := &models.User{}
-	err := r.pool.QueryRow(ctx,
-		"UPDATE users SET is_active = $1 WHERE user_id = $2 RETURNING user_id, username, team_name, is_active",
+	err := r.db.QueryRow(ctx,
+		"UPDATE users u SET is_active = $1 WHERE u.user_id = $2 RETURNING u.user_id, u.username, "+defaultTeamSQL+", u.is_active",
 		isActive, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserNotFound
 		}
-		return nil, err
+		return nil, fmt.Errorf("failed to update user %s: %w", userID, err)
 	}
 
 	return user, nil
-}
\ No newline at end of file
+}
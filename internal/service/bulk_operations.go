@@ -3,109 +3,173 @@ package service
 import (
 	"context"
 	"fmt"
-	"sync"
+	"slices"
 
 	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (s *PRService) BulkDeactivateTeamUsers(ctx context.Context, teamName string, userIDs []string) error {
-	userRepo := s.userRepo
-	prRepo := s.prRepo
+// BulkDeactivateTeamUsers deactivates the memberships of userIDs in teamName
+// and moves their OPEN reviews of the team's PRs to other active members. The
+// users stay active in their other teams. Everything happens in one
+// transaction: either all users are deactivated and reassigned or nothing is.
+func (s *PRService) BulkDeactivateTeamUsers(ctx context.Context, teamName string, userIDs []string) (_ *models.BulkDeactivationResult, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.BulkDeactivateTeamUsers", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
 
-	userIDMap := make(map[string]bool)
-	for _, id := range userIDs {
-		userIDMap[id] = true
+	if len(userIDs) == 0 {
+		return nil, ErrNoUsersToDeactivate
 	}
 
-	members, err := userRepo.GetTeamMembers(ctx, teamName)
-	if err != nil {
-		return err
+	result := &models.BulkDeactivationResult{
+		TeamName:         teamName,
+		DeactivatedUsers: []string{},
+		Replacements:     []models.ReviewerReplacement{},
 	}
 
-	var candidates []models.User
-	for _, member := range members {
-		if !userIDMap[member.UserID] && member.IsActive {
-			candidates = append(candidates, member)
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		members, err := tx.User.GetTeamMembers(ctx, teamName)
+		if err != nil {
+			return err
 		}
-	}
 
-	if len(candidates) == 0 {
-		return fmt.Errorf("no active candidates for replacement")
-	}
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(userIDs))
-	sem := make(chan struct{}, 3)
+		if len(members) == 0 {
+			return models.ErrTeamNotFound
+		}
 
-	for _, userID := range userIDs {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
+		inTeam := make(map[string]bool, len(members))
+		for _, member := range members {
+			inTeam[member.UserID] = true
+		}
 
-			prs, err := prRepo.GetUserReviews(ctx, id)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to get user reviews for %s: %w", id, err)
-				return
+		deactivated := make(map[string]bool, len(userIDs))
+		for _, id := range userIDs {
+			if !inTeam[id] {
+				return models.ErrUserNotFound.WithMessage(fmt.Sprintf("user %s is not a member of team %s", id, teamName))
+			}
+			if !deactivated[id] {
+				deactivated[id] = true
+				result.DeactivatedUsers = append(result.DeactivatedUsers, id)
 			}
+		}
 
-			for _, pr := range prs {
-				fullPR, err := prRepo.GetPR(ctx, pr.ID)
-				if err != nil || fullPR.Status != "OPEN" {
-					continue
+		// Replacements are picked one at a time so that every pick sees the
+		// open review counts produced by the previous ones.
+		for _, userID := range result.DeactivatedUsers {
+			err := forEachOpenReview(ctx, tx, teamName, userID, func(pr *models.PullRequest) error {
+				replacementID, err := s.pickReplacement(ctx, tx, pr, teamName, deactivated)
+				if err != nil {
+					return err
 				}
 
-				var replacement *models.User
-				for _, cand := range candidates {
-					isAssigned := false
-					for _, rev := range fullPR.AssignedReviewers {
-						if rev == cand.UserID {
-							isAssigned = true
-							break
-						}
-					}
-					if !isAssigned {
-						replacement = &cand
-						break
-					}
+				replacement, err := replaceReviewer(ctx, tx, pr.ID, userID, replacementID, models.ReassignReasonDeactivated)
+				if err != nil {
+					return err
 				}
+				result.Replacements = append(result.Replacements, replacement)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
 
-				if replacement == nil {
-				}
+			membership, err := tx.Membership.GetMembership(ctx, teamName, userID)
+			if err != nil {
+				return err
+			}
 
-				if err := prRepo.RemoveReviewer(ctx, pr.ID, id); err != nil {
-					errChan <- err
-					return
-				}
+			membership.IsActive = false
+			if err := tx.Membership.SaveMembership(ctx, membership); err != nil {
+				return err
+			}
 
-				if err := prRepo.AssignReviewers(ctx, pr.ID, []string{replacement.UserID}); err != nil {
-					errChan <- err
-					return
-				}
+			user, err := tx.User.GetUser(ctx, userID)
+			if err != nil {
+				return err
+			}
+
+			// The event reports the user as inactive in teamName only.
+			user.TeamName = teamName
+			user.IsActive = false
+			if err := enqueue(ctx, tx.Outbox, models.EventUserDeactivated, user); err != nil {
+				return err
 			}
-		}(userID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
-	close(errChan)
+	s.recordReplacements(result.Replacements)
+	return result, nil
+}
 
-	var lastErr error
-	for err := range errChan {
+// forEachOpenReview calls fn with every OPEN PR of teamName reviewerID is
+// assigned to. The PR is locked for the rest of the transaction.
+func forEachOpenReview(ctx context.Context, tx *repository.Repos, teamName string, reviewerID string, fn func(pr *models.PullRequest) error) error {
+	prs, err := tx.PR.GetUserReviews(ctx, reviewerID)
+	if err != nil {
+		return fmt.Errorf("failed to get user reviews for %s: %w", reviewerID, err)
+	}
+
+	for _, short := range prs {
+		if short.Status != models.PRStatusOpen {
+			continue
+		}
+
+		pr, err := tx.PR.GetPRForUpdate(ctx, short.ID)
 		if err != nil {
-			lastErr = err
+			return err
+		}
+
+		// The PR may have changed between listing and locking it.
+		if pr.Status != models.PRStatusOpen || pr.TeamName != teamName || !slices.Contains(pr.AssignedReviewers, reviewerID) {
+			continue
+		}
+
+		if err := fn(pr); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// replaceReviewer removes oldReviewerID from the PR, assigns newReviewerID
+// unless it is empty and enqueues reviewer.reassigned.
+func replaceReviewer(ctx context.Context, tx *repository.Repos, prID string, oldReviewerID string, newReviewerID string, reason string) (models.ReviewerReplacement, error) {
+	replacement := models.ReviewerReplacement{
+		PullRequestID: prID,
+		OldReviewerID: oldReviewerID,
+		NewReviewerID: newReviewerID,
+		LeftShort:     newReviewerID == "",
+		Reason:        reason,
+	}
 
-	if lastErr != nil {
-		return lastErr
+	if err := tx.PR.RemoveReviewer(ctx, prID, oldReviewerID); err != nil {
+		return replacement, err
 	}
 
-	for _, userID := range userIDs {
-		if _, err := userRepo.SetUserActive(ctx, userID, false); err != nil {
-			return err
+	if newReviewerID != "" {
+		if err := tx.PR.AssignReviewers(ctx, prID, []string{newReviewerID}); err != nil {
+			return replacement, err
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	return replacement, enqueue(ctx, tx.Outbox, models.EventReviewerReassigned, replacement)
+}
+
+// recordReplacements reports committed replacements to the metrics.
+func (s *PRService) recordReplacements(replacements []models.ReviewerReplacement) {
+	for _, replacement := range replacements {
+		if replacement.LeftShort {
+			s.metrics.NoCandidate()
+		} else {
+			s.metrics.ReviewerReassigned(replacement.Reason)
+		}
+	}
+}
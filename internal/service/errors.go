@@ -0,0 +1,19 @@
+package service
+
+import "pr-reviewer-service/internal/models"
+
+// Validation failures detected by services. They are reported as
+// VALIDATION_ERROR with the message below.
+var (
+	ErrUnknownStrategy        = models.ErrValidation.WithMessage("unknown reviewer_strategy")
+	ErrInvalidTeamSettings    = models.ErrValidation.WithMessage("reviewers_per_pr must be 1..10 and min_reviewers must be 0..reviewers_per_pr")
+	ErrInvalidReviewState     = models.ErrValidation.WithMessage("state must be one of APPROVED, CHANGES_REQUESTED, COMMENTED")
+	ErrNoUsersToDeactivate    = models.ErrValidation.WithMessage("user_ids must not be empty")
+	ErrNoUsersToRemove        = models.ErrValidation.WithMessage("user_ids must not be empty")
+	ErrNoMembersToAdd         = models.ErrValidation.WithMessage("members must not be empty")
+	ErrReassignToSameTeam     = models.ErrValidation.WithMessage("reassign_to must differ from team_name")
+	ErrUnknownRole            = models.ErrValidation.WithMessage("role must be one of member, lead")
+	ErrUnknownProvider        = models.ErrValidation.WithMessage("unknown provider")
+	ErrInvalidSubscriptionURL = models.ErrValidation.WithMessage("url must be an absolute http(s) URL")
+	ErrUnknownEventType       = models.ErrValidation.WithMessage("unknown event type")
+)
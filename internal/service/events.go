@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+func newEvent(eventType string, data any) (*models.Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Event{
+		ID:         newID(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
+
+// enqueue writes an event to the outbox. It must be called with the
+// repositories of the transaction that makes the change the event describes,
+// so the event is stored if and only if that change is committed.
+func enqueue(ctx context.Context, outbox repository.OutboxRepo, eventType string, data any) error {
+	event, err := newEvent(eventType, data)
+	if err != nil {
+		return fmt.Errorf("failed to build %s event: %w", eventType, err)
+	}
+	return outbox.Enqueue(ctx, event)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+type IdentityService struct {
+	store        repository.Transactor
+	identityRepo repository.IdentityRepo
+	userRepo     repository.UserRepo
+}
+
+func NewIdentityService(store repository.Transactor, identityRepo repository.IdentityRepo, userRepo repository.UserRepo) *IdentityService {
+	return &IdentityService{
+		store:        store,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+	}
+}
+
+func (s *IdentityService) SetMapping(ctx context.Context, mapping *models.IdentityMapping) (*models.IdentityMapping, error) {
+	if !isKnownProvider(mapping.Provider) {
+		return nil, ErrUnknownProvider
+	}
+
+	err := s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		if _, err := tx.User.GetUser(ctx, mapping.UserID); err != nil {
+			return err
+		}
+		return tx.Identity.SetMapping(ctx, mapping)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+func (s *IdentityService) ResolveUserID(ctx context.Context, provider string, login string) (string, error) {
+	return s.identityRepo.ResolveUserID(ctx, provider, login)
+}
+
+func isKnownProvider(provider string) bool {
+	return provider == models.ProviderGitHub || provider == models.ProviderGitLab
+}
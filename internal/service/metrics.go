@@ -0,0 +1,23 @@
+package service
+
+// Metrics receives domain events for monitoring. PRService reports an event
+// only after the transaction that produced it has committed.
+type Metrics interface {
+	// PRCreated counts a created pull request, draft or not.
+	PRCreated()
+	// ReviewersAssigned counts reviewers picked by strategy when a PR is
+	// opened, reopened or marked ready.
+	ReviewersAssigned(strategy string, count int)
+	// ReviewerReassigned counts a reviewer replaced for reason, one of
+	// models.ReassignReason*.
+	ReviewerReassigned(reason string)
+	// NoCandidate counts replacements that found no active candidate.
+	NoCandidate()
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) PRCreated()                    {}
+func (nopMetrics) ReviewersAssigned(string, int) {}
+func (nopMetrics) ReviewerReassigned(string)     {}
+func (nopMetrics) NoCandidate()                  {}
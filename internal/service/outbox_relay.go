@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"pr-reviewer-service/internal/logging"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+const (
+	outboxBatchSize    = 10
+	outboxPollInterval = time.Second
+	// outboxDispatchTimeout bounds one Dispatch, which makes a single
+	// attempt per subscription and leaves retries to the retry loop.
+	outboxDispatchTimeout = 30 * time.Second
+	// outboxLease must outlast dispatching a whole claimed batch, otherwise
+	// another relay could pick up an event that is still in flight.
+	outboxLease = outboxBatchSize*outboxDispatchTimeout + time.Minute
+
+	webhookRetryBatchSize = 10
+	// webhookRetryLease outlasts one RetryDue call, which is cut off at
+	// outboxDispatchTimeout.
+	webhookRetryLease = outboxDispatchTimeout + time.Minute
+)
+
+type eventSink interface {
+	Dispatch(ctx context.Context, event *models.Event) error
+	RetryDue(ctx context.Context, limit int, lease time.Duration) (int, error)
+}
+
+// OutboxRelay drains the outbox table into the webhook dispatcher and, in a
+// separate loop, makes the webhook retries that are due. An event is marked
+// dispatched only after Dispatch returns, so a crash in between leads to the
+// event being delivered again (at-least-once).
+type OutboxRelay struct {
+	outbox repository.OutboxRepo
+	sink   eventSink
+
+	mu      sync.Mutex
+	running bool
+	lastRun *time.Time
+}
+
+func NewOutboxRelay(outbox repository.OutboxRepo, sink eventSink) *OutboxRelay {
+	return &OutboxRelay{outbox: outbox, sink: sink}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.running = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		poll(ctx, "webhook retries failed", r.retry)
+	}()
+
+	poll(ctx, "outbox relay failed", r.drain)
+	wg.Wait()
+}
+
+// poll runs fn every outboxPollInterval until ctx is done.
+func poll(ctx context.Context, failure string, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).ErrorContext(ctx, failure, slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *OutboxRelay) drain(ctx context.Context) error {
+	for {
+		events, err := r.outbox.Claim(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			return err
+		}
+
+		// A failed event keeps its lease and is dispatched again once it
+		// expires; the rest of the batch goes out now instead of waiting
+		// for it.
+		var errs []error
+		for i := range events {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := r.dispatch(ctx, &events[i]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+
+		now := time.Now().UTC()
+		r.mu.Lock()
+		r.lastRun = &now
+		r.mu.Unlock()
+
+		if len(events) < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+func (r *OutboxRelay) dispatch(ctx context.Context, event *models.Event) error {
+	dispatchCtx, cancel := context.WithTimeout(ctx, outboxDispatchTimeout)
+	err := r.sink.Dispatch(dispatchCtx, event)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to dispatch event %s: %w", event.ID, err)
+	}
+
+	return r.outbox.MarkDispatched(ctx, event.ID)
+}
+
+func (r *OutboxRelay) retry(ctx context.Context) error {
+	for {
+		retryCtx, cancel := context.WithTimeout(ctx, outboxDispatchTimeout)
+		claimed, err := r.sink.RetryDue(retryCtx, webhookRetryBatchSize, webhookRetryLease)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if claimed < webhookRetryBatchSize {
+			return nil
+		}
+	}
+}
+
+// Stats reports how many events wait in the outbox, the age of the oldest
+// one and when the relay last finished a pass.
+func (r *OutboxRelay) Stats(ctx context.Context) (*models.OutboxStats, error) {
+	stats, err := r.outbox.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	stats.LastRunAt = r.lastRun
+	r.mu.Unlock()
+
+	return stats, nil
+}
+
+// Health returns the relay statistics and an error only when the relay is
+// not running. A relay that runs but makes no progress, because the database
+// or webhook receivers are down, must not take the service out of rotation;
+// the outbox metrics are there to alert on that. Statistics that cannot be
+// read are left out, the database check reports the cause.
+func (r *OutboxRelay) Health(ctx context.Context) (*models.OutboxStats, error) {
+	r.mu.Lock()
+	running := r.running
+	r.mu.Unlock()
+	if !running {
+		return nil, errors.New("outbox relay is not running")
+	}
+
+	stats, err := r.Stats(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	return stats, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+// flakySink fails the first Dispatch of every event listed in failOnce.
+type flakySink struct {
+	mu         sync.Mutex
+	failOnce   map[string]bool
+	dispatched []string
+}
+
+func (s *flakySink) Dispatch(ctx context.Context, event *models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failOnce[event.ID] {
+		delete(s.failOnce, event.ID)
+		return errors.New("subscriptions unavailable")
+	}
+	s.dispatched = append(s.dispatched, event.ID)
+	return nil
+}
+
+func (s *flakySink) RetryDue(context.Context, int, time.Duration) (int, error) { return 0, nil }
+
+func TestOutboxRelaySkipsFailedEvent(t *testing.T) {
+	ctx := context.Background()
+	outbox := repository.NewMemoryStore().Repos().Outbox
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := outbox.Enqueue(ctx, &models.Event{ID: id, Type: models.EventPRCreated, OccurredAt: time.Now().UTC(), Data: []byte(`{}`)}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	sink := &flakySink{failOnce: map[string]bool{"e1": true}}
+	relay := NewOutboxRelay(outbox, sink)
+	if err := relay.drain(ctx); err == nil {
+		t.Fatal("drain() succeeded, want the e1 failure reported")
+	}
+	if !slices.Equal(sink.dispatched, []string{"e2", "e3"}) {
+		t.Fatalf("dispatched = %v, want the rest of the batch despite the e1 failure", sink.dispatched)
+	}
+
+	// Only e1 is left, leased until it is due again.
+	stats, err := outbox.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Pending != 1 {
+		t.Fatalf("pending = %d, want 1", stats.Pending)
+	}
+	if err := relay.drain(ctx); err != nil {
+		t.Fatalf("second drain() error = %v", err)
+	}
+	if !slices.Equal(sink.dispatched, []string{"e2", "e3"}) {
+		t.Fatalf("dispatched = %v, e1 must wait for its lease", sink.dispatched)
+	}
+}
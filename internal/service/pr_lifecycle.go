@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// prTransitions lists the statuses a PR may move to from each status.
+// MERGED is terminal.
+var prTransitions = map[string][]string{
+	models.PRStatusDraft:  {models.PRStatusOpen, models.PRStatusClosed},
+	models.PRStatusOpen:   {models.PRStatusMerged, models.PRStatusClosed},
+	models.PRStatusClosed: {models.PRStatusOpen},
+}
+
+func canTransition(from string, to string) bool {
+	for _, status := range prTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkReady moves a DRAFT PR to OPEN and assigns reviewers to it.
+func (s *PRService) MarkReady(ctx context.Context, prID string) (_ *models.PullRequest, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.MarkReady", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	return s.openPR(ctx, prID, models.PRStatusDraft)
+}
+
+// ReopenPR moves a CLOSED PR back to OPEN with a fresh set of reviewers.
+func (s *PRService) ReopenPR(ctx context.Context, prID string) (_ *models.PullRequest, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.ReopenPR", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	return s.openPR(ctx, prID, models.PRStatusClosed)
+}
+
+// ClosePR closes a DRAFT or OPEN PR without merging and releases its reviewers.
+func (s *PRService) ClosePR(ctx context.Context, prID string) (_ *models.PullRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.ClosePR", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	var closed *models.PullRequest
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		pr, err := tx.PR.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		if pr.Status == models.PRStatusClosed {
+			closed = pr
+			return nil
+		}
+
+		if !canTransition(pr.Status, models.PRStatusClosed) {
+			return models.ErrInvalidTransition.WithMessage("only DRAFT or OPEN PR can be closed")
+		}
+
+		if err := tx.PR.RemoveAllReviewers(ctx, prID); err != nil {
+			return err
+		}
+
+		if err := tx.PR.SetStatus(ctx, prID, models.PRStatusClosed); err != nil {
+			return err
+		}
+
+		closed, err = tx.PR.GetPR(ctx, prID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return closed, nil
+}
+
+func (s *PRService) openPR(ctx context.Context, prID string, from string) (*models.PullRequest, string, error) {
+	var opened *models.PullRequest
+	var warning, strategy string
+	err := s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		pr, err := tx.PR.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		if pr.Status != from || !canTransition(pr.Status, models.PRStatusOpen) {
+			if from == models.PRStatusDraft {
+				return models.ErrInvalidTransition.WithMessage("only DRAFT PR can be marked ready")
+			}
+			return models.ErrInvalidTransition.WithMessage("only CLOSED PR can be reopened")
+		}
+
+		settings, candidates, err := s.reviewerCandidates(ctx, tx, pr.TeamName, pr.AuthorID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.PR.SetStatus(ctx, prID, models.PRStatusOpen); err != nil {
+			return err
+		}
+
+		reviewerIDs, w, err := s.assignInitialReviewers(ctx, tx, prID, pr.TeamName, settings, candidates)
+		if err != nil {
+			return err
+		}
+		warning = w
+		strategy = s.strategyFor(settings.ReviewerStrategy)
+
+		opened, err = tx.PR.GetPR(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		return enqueueAssignments(ctx, tx, prID, reviewerIDs)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.metrics.ReviewersAssigned(strategy, len(opened.AssignedReviewers))
+	return opened, warning, nil
+}
@@ -4,47 +4,162 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"log/slog"
 
+	"pr-reviewer-service/internal/logging"
 	"pr-reviewer-service/internal/models"
 	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PRService struct {
-	prRepo   *repository.PRRepository
-	userRepo *repository.UserRepository
-	teamRepo *repository.TeamRepository
+	store           repository.Transactor
+	prRepo          repository.PRRepo
+	userRepo        repository.UserRepo
+	teamRepo        repository.TeamRepo
+	defaultStrategy string
+	metrics         Metrics
 }
 
-func NewPRService(prRepo *repository.PRRepository, userRepo *repository.UserRepository, teamRepo *repository.TeamRepository) *PRService {
+// NewPRService creates the service. metrics may be nil.
+func NewPRService(store repository.Transactor, prRepo repository.PRRepo, userRepo repository.UserRepo, teamRepo repository.TeamRepo, defaultStrategy string, metrics Metrics) *PRService {
+	if !IsValidStrategy(defaultStrategy) {
+		defaultStrategy = models.StrategyRandom
+	}
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+
 	return &PRService{
-		prRepo:   prRepo,
-		userRepo: userRepo,
-		teamRepo: teamRepo,
+		store:           store,
+		prRepo:          prRepo,
+		userRepo:        userRepo,
+		teamRepo:        teamRepo,
+		defaultStrategy: defaultStrategy,
+		metrics:         metrics,
 	}
 }
 
-func (s *PRService) CreatePR(ctx context.Context, prID string, prName string, authorID string) (*models.PullRequest, error) {
-	author, err := s.userRepo.GetUser(ctx, authorID)
-	if err != nil {
-		return nil, fmt.Errorf("author not found")
+// CreatePR creates a PR in teamName, which the author has to be a member of,
+// and assigns reviewers from that team unless the PR is a draft. An empty
+// teamName means the author's default team.
+func (s *PRService) CreatePR(ctx context.Context, prID string, prName string, authorID string, teamName string, draft bool) (_ *models.PullRequest, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.CreatePR", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	status := models.PRStatusOpen
+	if draft {
+		status = models.PRStatusDraft
 	}
 
 	pr := &models.PullRequest{
 		ID:                prID,
 		Name:              prName,
 		AuthorID:          authorID,
-		Status:            "OPEN",
+		Status:            status,
 		AssignedReviewers: []string{},
 	}
 
-	if err := s.prRepo.CreatePR(ctx, pr); err != nil {
-		return nil, err
+	var warning, strategy string
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		author, err := tx.User.GetUser(ctx, authorID)
+		if errors.Is(err, models.ErrUserNotFound) {
+			return models.ErrUserNotFound.WithMessage("author not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		pr.TeamName = teamName
+		if pr.TeamName == "" {
+			pr.TeamName = author.TeamName
+		}
+		if pr.TeamName == "" {
+			return models.ErrTeamNotFound.WithMessage("author is not a member of any team")
+		}
+
+		_, err = tx.Membership.GetMembership(ctx, pr.TeamName, authorID)
+		if errors.Is(err, models.ErrMembershipNotFound) {
+			return models.ErrMembershipNotFound.WithMessage(fmt.Sprintf("author is not a member of team %s", pr.TeamName))
+		}
+		if err != nil {
+			return err
+		}
+
+		// Insert first so a duplicate id reports PR_EXISTS rather than
+		// whatever the reviewer lookup would fail with.
+		if err := tx.PR.CreatePR(ctx, pr); err != nil {
+			return err
+		}
+		if draft {
+			return enqueue(ctx, tx.Outbox, models.EventPRCreated, pr)
+		}
+
+		settings, candidates, err := s.reviewerCandidates(ctx, tx, pr.TeamName, authorID)
+		if err != nil {
+			return err
+		}
+
+		reviewerIDs, w, err := s.assignInitialReviewers(ctx, tx, pr.ID, pr.TeamName, settings, candidates)
+		if err != nil {
+			return err
+		}
+		pr.AssignedReviewers = reviewerIDs
+		warning = w
+		strategy = s.strategyFor(settings.ReviewerStrategy)
+
+		if err := enqueue(ctx, tx.Outbox, models.EventPRCreated, pr); err != nil {
+			return err
+		}
+		return enqueueAssignments(ctx, tx, pr.ID, reviewerIDs)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.metrics.PRCreated()
+	if !draft {
+		s.metrics.ReviewersAssigned(strategy, len(pr.AssignedReviewers))
+	}
+	return pr, warning, nil
+}
+
+func enqueueAssignments(ctx context.Context, tx *repository.Repos, prID string, reviewerIDs []string) error {
+	for _, reviewerID := range reviewerIDs {
+		err := enqueue(ctx, tx.Outbox, models.EventReviewerAssigned, models.ReviewerAssignment{
+			PullRequestID: prID,
+			ReviewerID:    reviewerID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reviewerCandidates returns the settings of the PR's team and its active
+// members except the author. It fails when the team requires more reviewers
+// than are available and does not allow assigning fewer, and when the team is
+// archived or was deleted.
+func (s *PRService) reviewerCandidates(ctx context.Context, repos *repository.Repos, teamName string, authorID string) (*models.TeamSettings, []models.User, error) {
+	if teamName == "" {
+		return nil, nil, models.ErrTeamNotFound.WithMessage("PR does not belong to a team")
 	}
 
-	members, err := s.userRepo.GetTeamMembers(ctx, author.TeamName)
+	settings, err := repos.Team.GetSettings(ctx, teamName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if settings.Archived {
+		return nil, nil, models.ErrTeamArchived
+	}
+
+	members, err := repos.User.GetTeamMembers(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var candidates []models.User
@@ -54,104 +169,327 @@ func (s *PRService) CreatePR(ctx context.Context, prID string, prName string, au
 		}
 	}
 
-	numReviewers := 2
-	if len(candidates) < 2 {
+	if len(candidates) < settings.MinReviewers && !settings.AllowFewerThanRequired {
+		return nil, nil, models.ErrNotEnoughReviewers
+	}
+
+	return settings, candidates, nil
+}
+
+func (s *PRService) assignInitialReviewers(ctx context.Context, repos *repository.Repos, prID string, teamName string, settings *models.TeamSettings, candidates []models.User) ([]string, string, error) {
+	numReviewers := settings.ReviewersPerPR
+	if len(candidates) < numReviewers {
 		numReviewers = len(candidates)
 	}
 
-	reviewerIDs := make([]string, 0, numReviewers)
+	reviewerIDs := []string{}
 	if numReviewers > 0 {
-		for i := len(candidates) - 1; i > 0; i-- {
-			j := rand.Intn(i + 1)
-			candidates[i], candidates[j] = candidates[j], candidates[i]
-		}
-
-		for i := 0; i < numReviewers; i++ {
-			reviewerIDs = append(reviewerIDs, candidates[i].UserID)
+		var err error
+		reviewerIDs, err = s.selectorFor(settings.ReviewerStrategy, repos).Select(ctx, teamName, candidates, numReviewers)
+		if err != nil {
+			return nil, "", err
 		}
 	}
 
+	logging.FromContext(ctx).DebugContext(ctx, "reviewers selected",
+		slog.String("pull_request_id", prID),
+		slog.String("team_name", teamName),
+		slog.String("strategy", s.strategyFor(settings.ReviewerStrategy)),
+		slog.Any("candidates", userIDs(candidates)),
+		slog.Any("chosen", reviewerIDs),
+		slog.Int("wanted", settings.ReviewersPerPR))
+
 	if len(reviewerIDs) > 0 {
-		if err := s.prRepo.AssignReviewers(ctx, prID, reviewerIDs); err != nil {
-			return nil, err
+		if err := repos.PR.AssignReviewers(ctx, prID, reviewerIDs); err != nil {
+			return nil, "", err
 		}
 	}
 
-	pr.AssignedReviewers = reviewerIDs
-	return pr, nil
-}
+	var warning string
+	if len(reviewerIDs) < settings.ReviewersPerPR {
+		warning = fmt.Sprintf("only %d of %d configured reviewers could be assigned", len(reviewerIDs), settings.ReviewersPerPR)
+	}
 
-func (s *PRService) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
-	return s.prRepo.MergePR(ctx, prID)
+	return reviewerIDs, warning, nil
 }
 
-func (s *PRService) ReassignReviewer(ctx context.Context, prID string, oldReviewerID string) (*models.PullRequest, string, error) {
-	pr, err := s.prRepo.GetPR(ctx, prID)
+// MergePR merges the PR once enough assigned reviewers approved it and no one
+// has outstanding change requests. force skips the check; forced merges are
+// recorded in the audit log together with the actor.
+func (s *PRService) MergePR(ctx context.Context, prID string, force bool, actor string) (_ *models.PullRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.MergePR", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	var merged *models.PullRequest
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		pr, err := tx.PR.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		if pr.Status == models.PRStatusMerged {
+			merged = pr
+			return nil
+		}
+
+		if !canTransition(pr.Status, models.PRStatusMerged) {
+			return models.ErrInvalidTransition.WithMessage("only OPEN PR can be merged")
+		}
+
+		approvals, required, err := s.approvalStatus(ctx, tx, pr)
+		if err != nil {
+			return err
+		}
+
+		if !force && (approvals < required || hasChangesRequested(pr)) {
+			return models.ErrNotApproved
+		}
+
+		merged, err = tx.PR.MergePR(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Audit.Record(ctx, &models.AuditEntry{
+			PullRequestID: prID,
+			Action:        "MERGE",
+			Actor:         actor,
+			Forced:        force,
+			Details:       fmt.Sprintf("approvals %d/%d, changes requested: %t", approvals, required, hasChangesRequested(pr)),
+		})
+		if err != nil {
+			return err
+		}
+
+		return enqueue(ctx, tx.Outbox, models.EventPRMerged, merged)
+	})
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// approvalStatus returns how many assigned reviewers approved pr and how many
+// approvals the PR's team requires. A PR left with fewer reviewers than
+// min_reviewers cannot reach the requirement and needs a forced merge.
+func (s *PRService) approvalStatus(ctx context.Context, repos *repository.Repos, pr *models.PullRequest) (int, int, error) {
+	// A PR whose team was deleted has no min_reviewers to apply, so every
+	// assigned reviewer has to approve, and there has to be at least one.
+	required := max(len(pr.Reviews), 1)
+	if pr.TeamName != "" {
+		settings, err := repos.Team.GetSettings(ctx, pr.TeamName)
+		if err != nil {
+			return 0, 0, err
+		}
+		required = settings.MinReviewers
 	}
 
-	if pr.Status == "MERGED" {
-		return nil, "", errors.New("PR is merged")
+	approvals := 0
+	for _, review := range pr.Reviews {
+		if review.State == models.ReviewApproved {
+			approvals++
+		}
 	}
 
-	isAssigned, err := s.prRepo.IsReviewerAssigned(ctx, prID, oldReviewerID)
+	return approvals, required, nil
+}
+
+func hasChangesRequested(pr *models.PullRequest) bool {
+	for _, review := range pr.Reviews {
+		if review.State == models.ReviewChangesRequested {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PRService) ReassignReviewer(ctx context.Context, prID string, oldReviewerID string) (_ *models.PullRequest, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.ReassignReviewer", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	var updatedPR *models.PullRequest
+	var newReviewerID string
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		pr, err := tx.PR.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		if pr.Status == models.PRStatusMerged {
+			return models.ErrPRMerged
+		}
+
+		if pr.Status != models.PRStatusOpen {
+			return models.ErrPRNotOpen
+		}
+
+		isAssigned, err := tx.PR.IsReviewerAssigned(ctx, prID, oldReviewerID)
+		if err != nil {
+			return err
+		}
+
+		if !isAssigned {
+			return models.ErrNotAssigned
+		}
+
+		// The replacement comes from the PR's team, whichever teams the old
+		// reviewer belongs to.
+		if pr.TeamName != "" {
+			settings, err := tx.Team.GetSettings(ctx, pr.TeamName)
+			if err != nil {
+				return err
+			}
+			if settings.Archived {
+				return models.ErrTeamArchived
+			}
+		}
+
+		newReviewerID, err = s.pickReplacement(ctx, tx, pr, pr.TeamName, map[string]bool{oldReviewerID: true})
+		if err != nil {
+			return err
+		}
+
+		if newReviewerID == "" {
+			return models.ErrNoCandidate
+		}
+
+		if err := tx.PR.RemoveReviewer(ctx, prID, oldReviewerID); err != nil {
+			return err
+		}
+
+		if err := tx.PR.AssignReviewers(ctx, prID, []string{newReviewerID}); err != nil {
+			return err
+		}
+
+		updatedPR, err = tx.PR.GetPR(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		return enqueue(ctx, tx.Outbox, models.EventReviewerReassigned, models.ReviewerReplacement{
+			PullRequestID: prID,
+			OldReviewerID: oldReviewerID,
+			NewReviewerID: newReviewerID,
+			Reason:        models.ReassignReasonManual,
+		})
+	})
+	if errors.Is(err, models.ErrNoCandidate) {
+		s.metrics.NoCandidate()
+	}
 	if err != nil {
 		return nil, "", err
 	}
 
-	if !isAssigned {
-		return nil, "", errors.New("reviewer is not assigned")
+	s.metrics.ReviewerReassigned(models.ReassignReasonManual)
+	return updatedPR, newReviewerID, nil
+}
+
+func (s *PRService) SubmitReview(ctx context.Context, prID string, reviewerID string, state string) (_ *models.PullRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.SubmitReview", attribute.String("pull_request.id", prID))
+	defer tracing.End(span, &err)
+
+	switch state {
+	case models.ReviewApproved, models.ReviewChangesRequested, models.ReviewCommented:
+	default:
+		return nil, ErrInvalidReviewState
 	}
 
-	oldReviewer, err := s.userRepo.GetUser(ctx, oldReviewerID)
+	var updated *models.PullRequest
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		pr, err := tx.PR.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+
+		if pr.Status == models.PRStatusMerged {
+			return models.ErrPRMerged
+		}
+
+		if pr.Status != models.PRStatusOpen {
+			return models.ErrPRNotOpen
+		}
+
+		if err := tx.PR.SetReviewState(ctx, prID, reviewerID, state); err != nil {
+			return err
+		}
+
+		updated, err = tx.PR.GetPR(ctx, prID)
+		return err
+	})
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	members, err := s.userRepo.GetTeamMembers(ctx, oldReviewer.TeamName)
+	return updated, nil
+}
+
+func (s *PRService) GetUserReviews(ctx context.Context, userID string) (_ []models.PullRequestShort, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.GetUserReviews", attribute.String("user.id", userID))
+	defer tracing.End(span, &err)
+
+	return s.prRepo.GetUserReviews(ctx, userID)
+}
+
+// pickReplacement selects one active member of teamName who is neither the
+// author nor already assigned to pr. It returns "" when nobody qualifies or
+// the team is archived. Replacements always go to the least loaded candidate,
+// whatever the team strategy, so that a reassignment wave does not pile up
+// on one reviewer.
+func (s *PRService) pickReplacement(ctx context.Context, repos *repository.Repos, pr *models.PullRequest, teamName string, excluded map[string]bool) (string, error) {
+	members, err := repos.User.GetTeamMembers(ctx, teamName)
 	if err != nil {
-		return nil, "", err
+		return "", err
+	}
+
+	assigned := make(map[string]bool, len(pr.AssignedReviewers))
+	for _, rev := range pr.AssignedReviewers {
+		assigned[rev] = true
 	}
 
 	var candidates []models.User
 	for _, member := range members {
-		if member.IsActive && member.UserID != oldReviewerID {
-			isDuplicate := false
-			for _, rev := range pr.AssignedReviewers {
-				if rev == member.UserID {
-					isDuplicate = true
-					break
-				}
-			}
-			if !isDuplicate {
-				candidates = append(candidates, member)
-			}
+		if !member.IsActive || member.UserID == pr.AuthorID || excluded[member.UserID] || assigned[member.UserID] {
+			continue
 		}
+		candidates = append(candidates, member)
 	}
 
+	logger := logging.FromContext(ctx)
 	if len(candidates) == 0 {
-		return nil, "", errors.New("no active replacement candidate in team")
+		logger.DebugContext(ctx, "no replacement candidate",
+			slog.String("pull_request_id", pr.ID),
+			slog.String("team_name", teamName))
+		return "", nil
 	}
 
-	newReviewerID := candidates[rand.Intn(len(candidates))].UserID
-
-	if err := s.prRepo.RemoveReviewer(ctx, prID, oldReviewerID); err != nil {
-		return nil, "", err
+	settings, err := repos.Team.GetSettings(ctx, teamName)
+	if err != nil {
+		return "", err
 	}
 
-	if err := s.prRepo.AssignReviewers(ctx, prID, []string{newReviewerID}); err != nil {
-		return nil, "", err
+	if settings.Archived {
+		logger.DebugContext(ctx, "no replacement candidate",
+			slog.String("pull_request_id", pr.ID),
+			slog.String("team_name", teamName),
+			slog.Bool("archived", true))
+		return "", nil
 	}
 
-	updatedPR, err := s.prRepo.GetPR(ctx, prID)
+	picked, err := (&leastLoadedSelector{loads: repos.PR}).Select(ctx, teamName, candidates, 1)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 
-	return updatedPR, newReviewerID, nil
-}
+	logger.DebugContext(ctx, "replacement selected",
+		slog.String("pull_request_id", pr.ID),
+		slog.String("team_name", teamName),
+		slog.String("strategy", models.StrategyLeastLoaded),
+		slog.Any("candidates", userIDs(candidates)),
+		slog.Any("chosen", picked))
 
-func (s *PRService) GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
-	return s.prRepo.GetUserReviews(ctx, userID)
-}
\ No newline at end of file
+	if len(picked) == 0 {
+		return "", nil
+	}
+	return picked[0], nil
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/logging"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+func newTestPRService(t *testing.T, members ...string) (*PRService, *repository.MemoryStore) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	repos := store.Repos()
+
+	teams := NewTeamService(store, repos.Team, repos.User)
+	team := &models.Team{TeamName: "backend"}
+	for _, id := range members {
+		team.Members = append(team.Members, models.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if _, err := teams.CreateTeam(context.Background(), team); err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+
+	return NewPRService(store, repos.PR, repos.User, repos.Team, models.StrategyLeastLoaded, nil), store
+}
+
+func TestCreatePRAssignsTeamReviewers(t *testing.T) {
+	svc, store := newTestPRService(t, "u1", "u2", "u3", "u4")
+	ctx := context.Background()
+
+	pr, warning, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	if warning != "" {
+		t.Fatalf("unexpected warning %q", warning)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("expected 2 reviewers, got %v", pr.AssignedReviewers)
+	}
+	for _, id := range pr.AssignedReviewers {
+		if id == "u1" {
+			t.Fatal("author must not review their own PR")
+		}
+	}
+
+	stats, err := store.Repos().Outbox.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Pending != 3 {
+		t.Fatalf("expected pr.created and two reviewer.assigned events, got %d", stats.Pending)
+	}
+
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Again", "u1", "", false); !errors.Is(err, models.ErrPRExists) {
+		t.Fatalf("expected PR already exists, got %v", err)
+	}
+}
+
+func TestCreatePRWarnsWhenTeamIsTooSmall(t *testing.T) {
+	svc, _ := newTestPRService(t, "u1", "u2")
+
+	pr, warning, err := svc.CreatePR(context.Background(), "pr-1", "Fix typo", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u2" {
+		t.Fatalf("expected u2 as the only reviewer, got %v", pr.AssignedReviewers)
+	}
+	if warning == "" {
+		t.Fatal("expected a warning about missing reviewers")
+	}
+}
+
+func TestCreatePRReportsDuplicateBeforeReviewerShortage(t *testing.T) {
+	svc, store := newTestPRService(t, "u1", "u2")
+	ctx := context.Background()
+
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Fix typo", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	settings := &models.TeamSettings{TeamName: "backend", ReviewersPerPR: 2, MinReviewers: 2}
+	if err := store.Repos().Team.UpdateSettings(ctx, settings); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	if _, _, err := svc.CreatePR(ctx, "pr-2", "Fix docs", "u1", "", false); !errors.Is(err, models.ErrNotEnoughReviewers) {
+		t.Fatalf("expected not enough reviewers, got %v", err)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Fix typo", "u1", "", false); !errors.Is(err, models.ErrPRExists) {
+		t.Fatalf("expected PR already exists, got %v", err)
+	}
+}
+
+func TestReassignReviewer(t *testing.T) {
+	svc, _ := newTestPRService(t, "u1", "u2", "u3", "u4")
+	ctx := context.Background()
+
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	old := pr.AssignedReviewers[0]
+	updated, replacement, err := svc.ReassignReviewer(ctx, "pr-1", old)
+	if err != nil {
+		t.Fatalf("ReassignReviewer: %v", err)
+	}
+	if replacement == old || replacement == "u1" {
+		t.Fatalf("unexpected replacement %q", replacement)
+	}
+	for _, id := range updated.AssignedReviewers {
+		if id == old {
+			t.Fatalf("%s is still assigned", old)
+		}
+	}
+
+	if _, err := svc.MergePR(ctx, "pr-1", true, "admin"); err != nil {
+		t.Fatalf("MergePR: %v", err)
+	}
+	if _, _, err := svc.ReassignReviewer(ctx, "pr-1", replacement); !errors.Is(err, models.ErrPRMerged) {
+		t.Fatalf("expected PR is merged, got %v", err)
+	}
+}
+
+func TestReassignReviewerPicksLeastLoadedWhateverTheStrategy(t *testing.T) {
+	svc, store := newTestPRService(t, "u1", "u2", "u3", "u4")
+	ctx := context.Background()
+	repos := store.Repos()
+
+	if err := repos.Team.UpdateSettings(ctx, &models.TeamSettings{
+		TeamName: "backend", ReviewerStrategy: models.StrategyRandom, ReviewersPerPR: 1, MinReviewers: 1,
+	}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	// u3 already reviews three open PRs, u4 none.
+	for _, pr := range []struct{ id, reviewer string }{
+		{"busy-1", "u3"}, {"busy-2", "u3"}, {"busy-3", "u3"},
+		{"pr-1", "u2"}, {"pr-2", "u2"}, {"pr-3", "u2"},
+	} {
+		if err := repos.PR.CreatePR(ctx, &models.PullRequest{ID: pr.id, Name: pr.id, AuthorID: "u1", TeamName: "backend", Status: models.PRStatusOpen}); err != nil {
+			t.Fatalf("CreatePR %s: %v", pr.id, err)
+		}
+		if err := repos.PR.AssignReviewers(ctx, pr.id, []string{pr.reviewer}); err != nil {
+			t.Fatalf("AssignReviewers %s: %v", pr.id, err)
+		}
+	}
+
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		_, replacement, err := svc.ReassignReviewer(ctx, id, "u2")
+		if err != nil {
+			t.Fatalf("ReassignReviewer %s: %v", id, err)
+		}
+		if replacement != "u4" {
+			t.Fatalf("%s replacement = %s, want the least loaded u4", id, replacement)
+		}
+	}
+}
+
+func TestMergePRWithoutReviewersNeedsForce(t *testing.T) {
+	svc, teams, _ := newTestTeams(t, "u1")
+	createTeam(t, teams, "frontend", "f1")
+	ctx := context.Background()
+
+	for _, pr := range []struct{ id, author, team string }{
+		{"pr-1", "u1", "backend"},
+		{"pr-2", "f1", "frontend"},
+	} {
+		created, _, err := svc.CreatePR(ctx, pr.id, "Solo change", pr.author, pr.team, false)
+		if err != nil {
+			t.Fatalf("CreatePR %s: %v", pr.id, err)
+		}
+		if len(created.AssignedReviewers) != 0 {
+			t.Fatalf("%s reviewers = %v, want none", pr.id, created.AssignedReviewers)
+		}
+	}
+	// pr-2 loses its team and with it min_reviewers.
+	if _, err := svc.RemoveTeamMembers(ctx, "frontend", []string{"f1"}); err != nil {
+		t.Fatalf("RemoveTeamMembers: %v", err)
+	}
+	if err := teams.DeleteTeam(ctx, "frontend"); err != nil {
+		t.Fatalf("DeleteTeam: %v", err)
+	}
+
+	for _, id := range []string{"pr-1", "pr-2"} {
+		if _, err := svc.MergePR(ctx, id, false, ""); !errors.Is(err, models.ErrNotApproved) {
+			t.Fatalf("merging %s without reviewers: %v", id, err)
+		}
+		merged, err := svc.MergePR(ctx, id, true, "admin")
+		if err != nil {
+			t.Fatalf("forced MergePR %s: %v", id, err)
+		}
+		if merged.Status != models.PRStatusMerged {
+			t.Fatalf("%s status = %s", id, merged.Status)
+		}
+	}
+}
+
+func TestReassignReviewerWithoutCandidates(t *testing.T) {
+	svc, _ := newTestPRService(t, "u1", "u2", "u3")
+	ctx := context.Background()
+
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	_, _, err := svc.ReassignReviewer(ctx, "pr-1", "u2")
+	if !errors.Is(err, models.ErrNoCandidate) {
+		t.Fatalf("expected no candidate error, got %v", err)
+	}
+
+	pr, err := svc.prRepo.GetPR(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("GetPR: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("failed reassignment must not change reviewers, got %v", pr.AssignedReviewers)
+	}
+}
+
+func TestPRReviewersComeFromPRTeam(t *testing.T) {
+	svc, teams, _ := newTestTeams(t, "u1", "u2", "u3")
+	createTeam(t, teams, "frontend", "f1", "f2", "u2")
+	ctx := context.Background()
+
+	// Without a team the PR goes to the team u2 joined first.
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u2", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	slices.Sort(pr.AssignedReviewers)
+	if pr.TeamName != "backend" || !slices.Equal(pr.AssignedReviewers, []string{"u1", "u3"}) {
+		t.Fatalf("default team PR = %+v", pr)
+	}
+
+	pr, _, err = svc.CreatePR(ctx, "pr-2", "Fix layout", "u2", "frontend", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	slices.Sort(pr.AssignedReviewers)
+	if pr.TeamName != "frontend" || !slices.Equal(pr.AssignedReviewers, []string{"f1", "f2"}) {
+		t.Fatalf("frontend PR = %+v", pr)
+	}
+
+	if _, _, err := svc.CreatePR(ctx, "pr-3", "Elsewhere", "u1", "frontend", false); !errors.Is(err, models.ErrMembershipNotFound) {
+		t.Fatalf("CreatePR in a team of someone else: %v", err)
+	}
+
+	// backend members are not candidates for a frontend PR.
+	if _, _, err := svc.ReassignReviewer(ctx, "pr-2", "f1"); !errors.Is(err, models.ErrNoCandidate) {
+		t.Fatalf("ReassignReviewer without frontend candidates: %v", err)
+	}
+	if _, err := teams.AddMembers(ctx, "frontend", []models.TeamMember{{UserID: "u3", Username: "u3", IsActive: true}}); err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	if _, replacement, err := svc.ReassignReviewer(ctx, "pr-2", "f1"); err != nil || replacement != "u3" {
+		t.Fatalf("ReassignReviewer = %q, %v; want u3", replacement, err)
+	}
+
+	// Deactivating u3 in backend moves only the backend review.
+	result, err := svc.BulkDeactivateTeamUsers(ctx, "backend", []string{"u3"})
+	if err != nil {
+		t.Fatalf("BulkDeactivateTeamUsers: %v", err)
+	}
+	if len(result.Replacements) != 1 || result.Replacements[0].PullRequestID != "pr-1" {
+		t.Fatalf("replacements = %+v", result.Replacements)
+	}
+	team, err := teams.GetTeam(ctx, "frontend")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if i := slices.IndexFunc(team.Members, func(m models.TeamMember) bool { return m.UserID == "u3" }); i < 0 || !team.Members[i].IsActive {
+		t.Fatalf("u3 must stay active in frontend: %+v", team.Members)
+	}
+}
+
+func TestUserServiceGetUserReviews(t *testing.T) {
+	svc, store := newTestPRService(t, "u1", "u2")
+	ctx := context.Background()
+	repos := store.Repos()
+	users := NewUserService(store, repos.User, repos.PR)
+
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	prs, err := users.GetUserReviews(ctx, "u2")
+	if err != nil {
+		t.Fatalf("GetUserReviews: %v", err)
+	}
+	if len(prs) != 1 || prs[0].ID != "pr-1" {
+		t.Fatalf("unexpected reviews %+v", prs)
+	}
+
+	prs, err = users.GetUserReviews(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUserReviews: %v", err)
+	}
+	if prs == nil || len(prs) != 0 {
+		t.Fatalf("expected an empty list, got %#v", prs)
+	}
+}
+
+type recordedMetrics struct {
+	created     int
+	assigned    map[string]int
+	reassigned  map[string]int
+	noCandidate int
+}
+
+func (m *recordedMetrics) PRCreated() {
+	m.created++
+}
+
+func (m *recordedMetrics) ReviewersAssigned(strategy string, count int) {
+	m.assigned[strategy] += count
+}
+
+func (m *recordedMetrics) ReviewerReassigned(reason string) {
+	m.reassigned[reason]++
+}
+
+func (m *recordedMetrics) NoCandidate() {
+	m.noCandidate++
+}
+
+func TestPRServiceReportsCommittedOperations(t *testing.T) {
+	base, store := newTestPRService(t, "u1", "u2", "u3")
+	repos := store.Repos()
+	m := &recordedMetrics{assigned: map[string]int{}, reassigned: map[string]int{}}
+	svc := NewPRService(store, repos.PR, repos.User, repos.Team, base.defaultStrategy, m)
+	ctx := context.Background()
+
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Again", "u1", "", false); err == nil {
+		t.Fatal("expected a duplicate PR error")
+	}
+	if _, _, err := svc.ReassignReviewer(ctx, "pr-1", "u2"); !errors.Is(err, models.ErrNoCandidate) {
+		t.Fatalf("expected no candidate error, got %v", err)
+	}
+	result, err := svc.BulkDeactivateTeamUsers(ctx, "backend", []string{"u3"})
+	if err != nil {
+		t.Fatalf("BulkDeactivateTeamUsers: %v", err)
+	}
+	if len(result.Replacements) != 1 || !result.Replacements[0].LeftShort {
+		t.Fatalf("unexpected replacements %+v", result.Replacements)
+	}
+
+	if m.created != 1 {
+		t.Errorf("created = %d, failed creations must not count", m.created)
+	}
+	if m.assigned[models.StrategyLeastLoaded] != 2 {
+		t.Errorf("assigned = %v", m.assigned)
+	}
+	if len(m.reassigned) != 0 || m.noCandidate != 2 {
+		t.Errorf("reassigned = %v, no candidate = %d", m.reassigned, m.noCandidate)
+	}
+}
+
+func TestCreatePRLogsAssignmentDecision(t *testing.T) {
+	svc, _ := newTestPRService(t, "u1", "u2", "u3")
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx := logging.WithContext(context.Background(), logger)
+
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	var decision struct {
+		Msg        string   `json:"msg"`
+		Strategy   string   `json:"strategy"`
+		Candidates []string `json:"candidates"`
+		Chosen     []string `json:"chosen"`
+	}
+	for line := range strings.Lines(buf.String()) {
+		if strings.Contains(line, `"reviewers selected"`) {
+			if err := json.Unmarshal([]byte(line), &decision); err != nil {
+				t.Fatalf("decode %s: %v", line, err)
+			}
+		}
+	}
+
+	if decision.Strategy != models.StrategyLeastLoaded {
+		t.Fatalf("no assignment decision logged:\n%s", buf.String())
+	}
+	if !slices.Equal(decision.Candidates, []string{"u2", "u3"}) || !slices.Equal(decision.Chosen, pr.AssignedReviewers) {
+		t.Errorf("unexpected decision %+v, assigned %v", decision, pr.AssignedReviewers)
+	}
+}
+
+func TestRoundRobinRotationFollowsTransaction(t *testing.T) {
+	svc, store := newTestPRService(t, "u1", "u2", "u3")
+	ctx := context.Background()
+	candidates := []models.User{{UserID: "u1"}, {UserID: "u2"}, {UserID: "u3"}}
+	errRollback := errors.New("rollback")
+
+	pick := func(rollback bool) []string {
+		t.Helper()
+		var picked []string
+		err := store.WithTx(ctx, func(tx *repository.Repos) error {
+			var err error
+			picked, err = svc.selectorFor(models.StrategyRoundRobin, tx).Select(ctx, "backend", candidates, 1)
+			if err == nil && rollback {
+				err = errRollback
+			}
+			return err
+		})
+		if err != nil && !errors.Is(err, errRollback) {
+			t.Fatalf("Select: %v", err)
+		}
+		return picked
+	}
+
+	if got := pick(false); !slices.Equal(got, []string{"u1"}) {
+		t.Fatalf("first pick = %v, want u1", got)
+	}
+	if got := pick(true); !slices.Equal(got, []string{"u2"}) {
+		t.Fatalf("rolled back pick = %v, want u2", got)
+	}
+	// The rolled back pick must not have moved the rotation.
+	if got := pick(false); !slices.Equal(got, []string{"u2"}) {
+		t.Fatalf("pick after rollback = %v, want u2", got)
+	}
+}
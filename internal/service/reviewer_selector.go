@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+// ReviewerSelector picks up to n reviewers out of the given candidates.
+// Candidates are already filtered (active, not the author, not assigned yet).
+type ReviewerSelector interface {
+	Select(ctx context.Context, teamName string, candidates []models.User, n int) ([]string, error)
+}
+
+type reviewLoadCounter interface {
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+}
+
+type roundRobinCursors interface {
+	GetRoundRobinCursorForUpdate(ctx context.Context, teamName string) (string, error)
+	SetRoundRobinCursor(ctx context.Context, teamName string, userID string) error
+}
+
+func IsValidStrategy(name string) bool {
+	switch name {
+	case models.StrategyRandom, models.StrategyRoundRobin, models.StrategyLeastLoaded, models.StrategyWeighted:
+		return true
+	}
+	return false
+}
+
+// strategyFor returns the strategy a team with the given setting uses.
+func (s *PRService) strategyFor(strategy string) string {
+	if !IsValidStrategy(strategy) {
+		return s.defaultStrategy
+	}
+	return strategy
+}
+
+// selectorFor returns the selector of strategy bound to repos, so that
+// whatever it reads or stores is part of the caller's transaction.
+func (s *PRService) selectorFor(strategy string, repos *repository.Repos) ReviewerSelector {
+	switch s.strategyFor(strategy) {
+	case models.StrategyRoundRobin:
+		return &roundRobinSelector{cursors: repos.Team}
+	case models.StrategyLeastLoaded:
+		return &leastLoadedSelector{loads: repos.PR}
+	case models.StrategyWeighted:
+		return &weightedSelector{loads: repos.PR}
+	}
+	return &randomSelector{}
+}
+
+type randomSelector struct{}
+
+func (s *randomSelector) Select(ctx context.Context, teamName string, candidates []models.User, n int) ([]string, error) {
+	shuffled := make([]models.User, len(candidates))
+	copy(shuffled, candidates)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return firstIDs(shuffled, n), nil
+}
+
+// roundRobinSelector continues from the user_id after the last reviewer
+// handed out in the team, so it keeps working when members change. The last
+// reviewer is stored with the team in the caller's transaction: a rolled back
+// assignment does not move the rotation, and all replicas share it.
+type roundRobinSelector struct {
+	cursors roundRobinCursors
+}
+
+func (s *roundRobinSelector) Select(ctx context.Context, teamName string, candidates []models.User, n int) ([]string, error) {
+	if len(candidates) == 0 || n <= 0 {
+		return []string{}, nil
+	}
+
+	ordered := make([]models.User, len(candidates))
+	copy(ordered, candidates)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].UserID < ordered[j].UserID
+	})
+
+	last, err := s.cursors.GetRoundRobinCursorForUpdate(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	start := sort.Search(len(ordered), func(i int) bool {
+		return ordered[i].UserID > last
+	})
+
+	if n > len(ordered) {
+		n = len(ordered)
+	}
+
+	reviewerIDs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		reviewerIDs = append(reviewerIDs, ordered[(start+i)%len(ordered)].UserID)
+	}
+
+	if err := s.cursors.SetRoundRobinCursor(ctx, teamName, reviewerIDs[len(reviewerIDs)-1]); err != nil {
+		return nil, err
+	}
+	return reviewerIDs, nil
+}
+
+type leastLoadedSelector struct {
+	loads reviewLoadCounter
+}
+
+func (s *leastLoadedSelector) Select(ctx context.Context, teamName string, candidates []models.User, n int) ([]string, error) {
+	counts, err := s.loads.CountOpenReviews(ctx, userIDs(candidates))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open reviews: %w", err)
+	}
+
+	// Shuffle before the stable sort so that ties are broken randomly
+	// instead of always favouring the lowest user_id.
+	ordered := make([]models.User, len(candidates))
+	copy(ordered, candidates)
+	rand.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return counts[ordered[i].UserID] < counts[ordered[j].UserID]
+	})
+
+	return firstIDs(ordered, n), nil
+}
+
+// weightedSelector draws reviewers at random with a probability inversely
+// proportional to the number of open reviews they already have.
+type weightedSelector struct {
+	loads reviewLoadCounter
+}
+
+func (s *weightedSelector) Select(ctx context.Context, teamName string, candidates []models.User, n int) ([]string, error) {
+	counts, err := s.loads.CountOpenReviews(ctx, userIDs(candidates))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open reviews: %w", err)
+	}
+
+	pool := make([]models.User, len(candidates))
+	copy(pool, candidates)
+
+	reviewerIDs := make([]string, 0, n)
+	for len(reviewerIDs) < n && len(pool) > 0 {
+		total := 0.0
+		for _, c := range pool {
+			total += 1 / float64(1+counts[c.UserID])
+		}
+
+		pick := len(pool) - 1
+		r := rand.Float64() * total
+		for i, c := range pool {
+			r -= 1 / float64(1+counts[c.UserID])
+			if r < 0 {
+				pick = i
+				break
+			}
+		}
+
+		reviewerIDs = append(reviewerIDs, pool[pick].UserID)
+		pool = append(pool[:pick], pool[pick+1:]...)
+	}
+
+	return reviewerIDs, nil
+}
+
+func userIDs(users []models.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.UserID)
+	}
+	return ids
+}
+
+func firstIDs(users []models.User, n int) []string {
+	if n > len(users) {
+		n = len(users)
+	}
+
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, users[i].UserID)
+	}
+	return ids
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"net/url"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+var knownEventTypes = map[string]bool{
+	models.EventPRCreated:          true,
+	models.EventReviewerAssigned:   true,
+	models.EventReviewerReassigned: true,
+	models.EventPRMerged:           true,
+	models.EventUserDeactivated:    true,
+}
+
+type SubscriptionService struct {
+	store   repository.Transactor
+	subRepo repository.SubscriptionRepo
+}
+
+func NewSubscriptionService(store repository.Transactor, subRepo repository.SubscriptionRepo) *SubscriptionService {
+	return &SubscriptionService{store: store, subRepo: subRepo}
+}
+
+// CreateSubscription stores a new subscription. A signing secret is generated
+// when none is given; it is only returned from this call.
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	sub.ID = newID()
+	if sub.Secret == "" {
+		sub.Secret = newID()
+	}
+
+	if err := s.subRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *SubscriptionService) GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	sub, err := s.subRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs, err := s.subRepo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+// UpdateSubscription replaces url, events and is_active. The secret is kept
+// unless a new one is given.
+func (s *SubscriptionService) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	err := s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		current, err := tx.Subscription.GetSubscription(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+
+		if sub.Secret == "" {
+			sub.Secret = current.Secret
+		}
+		sub.CreatedAt = current.CreatedAt
+
+		return tx.Subscription.UpdateSubscription(ctx, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, id string) error {
+	return s.subRepo.DeleteSubscription(ctx, id)
+}
+
+func validateSubscription(sub *models.WebhookSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidSubscriptionURL
+	}
+
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+
+	for _, eventType := range sub.Events {
+		if !knownEventTypes[eventType] {
+			return ErrUnknownEventType
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RemoveTeamMembers removes userIDs from teamName and moves their OPEN reviews
+// of the team's PRs to the remaining active members. The users stay in their
+// other teams; users left without a team are kept, so their PRs and past
+// reviews stay intact, and can be added to a team again.
+func (s *PRService) RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string) (_ *models.MemberRemovalResult, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.RemoveTeamMembers", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	if len(userIDs) == 0 {
+		return nil, ErrNoUsersToRemove
+	}
+
+	result := &models.MemberRemovalResult{
+		TeamName:     teamName,
+		RemovedUsers: []string{},
+		Replacements: []models.ReviewerReplacement{},
+	}
+
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		if _, err := tx.Team.GetSettings(ctx, teamName); err != nil {
+			return err
+		}
+
+		members, err := tx.User.GetTeamMembers(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		inTeam := make(map[string]bool, len(members))
+		for _, member := range members {
+			inTeam[member.UserID] = true
+		}
+
+		removed := make(map[string]bool, len(userIDs))
+		for _, id := range userIDs {
+			if !inTeam[id] {
+				return models.ErrUserNotFound.WithMessage(fmt.Sprintf("user %s is not a member of team %s", id, teamName))
+			}
+			if !removed[id] {
+				removed[id] = true
+				result.RemovedUsers = append(result.RemovedUsers, id)
+			}
+		}
+
+		for _, userID := range result.RemovedUsers {
+			err := forEachOpenReview(ctx, tx, teamName, userID, func(pr *models.PullRequest) error {
+				replacementID, err := s.pickReplacement(ctx, tx, pr, teamName, removed)
+				if err != nil {
+					return err
+				}
+
+				replacement, err := replaceReviewer(ctx, tx, pr.ID, userID, replacementID, models.ReassignReasonRemoved)
+				if err != nil {
+					return err
+				}
+				result.Replacements = append(result.Replacements, replacement)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Membership.DeleteMembership(ctx, teamName, userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordReplacements(result.Replacements)
+	return result, nil
+}
+
+// ArchiveTeam stops all new reviewer assignments in teamName. OPEN reviews of
+// the team's PRs held by its members move to active members of reassignTo;
+// reviews that cannot be moved, or all of them when reassignTo is empty, keep
+// their reviewer and are returned as flagged.
+func (s *PRService) ArchiveTeam(ctx context.Context, teamName string, reassignTo string) (_ *models.TeamArchiveResult, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.ArchiveTeam", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	if reassignTo == teamName {
+		return nil, ErrReassignToSameTeam
+	}
+
+	result := &models.TeamArchiveResult{
+		TeamName:     teamName,
+		ReassignTo:   reassignTo,
+		Replacements: []models.ReviewerReplacement{},
+		Flagged:      []models.ReviewerAssignment{},
+	}
+
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		settings, err := tx.Team.GetSettings(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		if settings.Archived {
+			return models.ErrTeamArchived
+		}
+
+		if reassignTo != "" {
+			target, err := tx.Team.GetSettings(ctx, reassignTo)
+			if errors.Is(err, models.ErrTeamNotFound) {
+				return models.ErrTeamNotFound.WithMessage("reassign_to team not found")
+			}
+			if err != nil {
+				return err
+			}
+			if target.Archived {
+				return models.ErrTeamArchived.WithMessage("reassign_to team is archived")
+			}
+		}
+
+		if err := tx.Team.ArchiveTeam(ctx, teamName); err != nil {
+			return err
+		}
+
+		members, err := tx.User.GetTeamMembers(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			err := forEachOpenReview(ctx, tx, teamName, member.UserID, func(pr *models.PullRequest) error {
+				var replacementID string
+				if reassignTo != "" {
+					var err error
+					replacementID, err = s.pickReplacement(ctx, tx, pr, reassignTo, nil)
+					if err != nil {
+						return err
+					}
+				}
+
+				if replacementID == "" {
+					result.Flagged = append(result.Flagged, models.ReviewerAssignment{
+						PullRequestID: pr.ID,
+						ReviewerID:    member.UserID,
+					})
+					return nil
+				}
+
+				replacement, err := replaceReviewer(ctx, tx, pr.ID, member.UserID, replacementID, models.ReassignReasonArchived)
+				if err != nil {
+					return err
+				}
+				result.Replacements = append(result.Replacements, replacement)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordReplacements(result.Replacements)
+	if reassignTo != "" {
+		for range result.Flagged {
+			s.metrics.NoCandidate()
+		}
+	}
+	return result, nil
+}
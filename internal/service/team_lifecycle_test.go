@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+)
+
+// newTestTeams returns a PRService over "backend" with members and a
+// TeamService over the same store.
+func newTestTeams(t *testing.T, members ...string) (*PRService, *TeamService, *recordedMetrics) {
+	t.Helper()
+	base, store := newTestPRService(t, members...)
+	repos := store.Repos()
+	m := &recordedMetrics{assigned: map[string]int{}, reassigned: map[string]int{}}
+	svc := NewPRService(store, repos.PR, repos.User, repos.Team, base.defaultStrategy, m)
+	return svc, NewTeamService(store, repos.Team, repos.User), m
+}
+
+func createTeam(t *testing.T, teams *TeamService, teamName string, members ...string) {
+	t.Helper()
+	team := &models.Team{TeamName: teamName}
+	for _, id := range members {
+		team.Members = append(team.Members, models.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if _, err := teams.CreateTeam(context.Background(), team); err != nil {
+		t.Fatalf("create team %s: %v", teamName, err)
+	}
+}
+
+func TestRemoveTeamMembersReassignsOpenReviews(t *testing.T) {
+	svc, teams, m := newTestTeams(t, "u1", "u2", "u3", "u4")
+	ctx := context.Background()
+
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	removedID := pr.AssignedReviewers[0]
+
+	result, err := svc.RemoveTeamMembers(ctx, "backend", []string{removedID, "u1"})
+	if err != nil {
+		t.Fatalf("RemoveTeamMembers: %v", err)
+	}
+	if len(result.Replacements) != 1 || result.Replacements[0].OldReviewerID != removedID || result.Replacements[0].LeftShort {
+		t.Fatalf("replacements = %+v", result.Replacements)
+	}
+	if m.reassigned[models.ReassignReasonRemoved] != 1 {
+		t.Errorf("reassigned = %v", m.reassigned)
+	}
+
+	team, err := teams.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if len(team.Members) != 2 {
+		t.Fatalf("members after removal = %+v", team.Members)
+	}
+
+	if _, err := svc.RemoveTeamMembers(ctx, "backend", []string{"u1"}); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("removing a non-member: %v", err)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-2", "Orphan", "u1", "", false); !errors.Is(err, models.ErrTeamNotFound) {
+		t.Fatalf("CreatePR by a user without a team: %v", err)
+	}
+
+	// The PR stays in backend after its author left, so the team's
+	// min_reviewers still applies.
+	pr, err = svc.prRepo.GetPR(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("GetPR: %v", err)
+	}
+	if pr.TeamName != "backend" {
+		t.Fatalf("PR team = %q, want backend", pr.TeamName)
+	}
+	if _, err := svc.MergePR(ctx, "pr-1", false, ""); !errors.Is(err, models.ErrNotApproved) {
+		t.Fatalf("merge without approvals: %v", err)
+	}
+	if _, err := svc.SubmitReview(ctx, "pr-1", pr.AssignedReviewers[0], models.ReviewApproved); err != nil {
+		t.Fatalf("SubmitReview: %v", err)
+	}
+	if _, err := svc.MergePR(ctx, "pr-1", false, ""); err != nil {
+		t.Fatalf("MergePR: %v", err)
+	}
+
+	if _, err := teams.AddMembers(ctx, "backend", []models.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}}); err != nil {
+		t.Fatalf("adding a removed user back: %v", err)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-2", "Back again", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR after rejoining: %v", err)
+	}
+}
+
+func TestArchiveTeamReassignsOrFlagsOpenReviews(t *testing.T) {
+	svc, teams, m := newTestTeams(t, "u1", "u2", "u3")
+	createTeam(t, teams, "frontend", "f1")
+	ctx := context.Background()
+
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+
+	if _, err := svc.ArchiveTeam(ctx, "backend", "backend"); !errors.Is(err, ErrReassignToSameTeam) {
+		t.Fatalf("reassigning to the archived team itself: %v", err)
+	}
+	if _, err := svc.ArchiveTeam(ctx, "backend", "missing"); !errors.Is(err, models.ErrTeamNotFound) {
+		t.Fatalf("reassigning to a missing team: %v", err)
+	}
+
+	// frontend has a single member, so only one of the two reviews can move.
+	result, err := svc.ArchiveTeam(ctx, "backend", "frontend")
+	if err != nil {
+		t.Fatalf("ArchiveTeam: %v", err)
+	}
+	if len(result.Replacements) != 1 || result.Replacements[0].NewReviewerID != "f1" || result.Replacements[0].Reason != models.ReassignReasonArchived {
+		t.Fatalf("replacements = %+v", result.Replacements)
+	}
+	if len(result.Flagged) != 1 || !slices.Contains(pr.AssignedReviewers, result.Flagged[0].ReviewerID) {
+		t.Fatalf("flagged = %+v", result.Flagged)
+	}
+	if m.reassigned[models.ReassignReasonArchived] != 1 || m.noCandidate != 1 {
+		t.Errorf("reassigned = %v, no candidate = %d", m.reassigned, m.noCandidate)
+	}
+
+	pr, err = svc.prRepo.GetPR(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("GetPR: %v", err)
+	}
+	want := []string{result.Flagged[0].ReviewerID, "f1"}
+	slices.Sort(want)
+	if !slices.Equal(pr.AssignedReviewers, want) {
+		t.Fatalf("reviewers after archiving = %v, want %v", pr.AssignedReviewers, want)
+	}
+
+	if _, err := svc.ArchiveTeam(ctx, "backend", ""); !errors.Is(err, models.ErrTeamArchived) {
+		t.Fatalf("archiving twice: %v", err)
+	}
+	if _, err := svc.ArchiveTeam(ctx, "frontend", "backend"); !errors.Is(err, models.ErrTeamArchived) {
+		t.Fatalf("reassigning to an archived team: %v", err)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-2", "Late change", "u2", "", false); !errors.Is(err, models.ErrTeamArchived) {
+		t.Fatalf("CreatePR in an archived team: %v", err)
+	}
+	if _, _, err := svc.ReassignReviewer(ctx, "pr-1", result.Flagged[0].ReviewerID); !errors.Is(err, models.ErrTeamArchived) {
+		t.Fatalf("ReassignReviewer in an archived team: %v", err)
+	}
+	if _, err := teams.AddMembers(ctx, "backend", []models.TeamMember{{UserID: "u9", Username: "u9", IsActive: true}}); !errors.Is(err, models.ErrTeamArchived) {
+		t.Fatalf("AddMembers to an archived team: %v", err)
+	}
+}
+
+func TestTeamLifecycleConflicts(t *testing.T) {
+	svc, teams, _ := newTestTeams(t, "u1", "u2")
+	createTeam(t, teams, "frontend", "f1")
+	ctx := context.Background()
+
+	if _, err := teams.AddMembers(ctx, "frontend", []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}); err != nil {
+		t.Fatalf("adding a member of another team: %v", err)
+	}
+	_, err := teams.AddMembers(ctx, "frontend", []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}})
+	if !errors.Is(err, models.ErrMemberExists) {
+		t.Fatalf("adding a member twice: %v", err)
+	}
+	if _, err := teams.RenameTeam(ctx, "backend", "frontend"); !errors.Is(err, models.ErrTeamExists) {
+		t.Fatalf("renaming onto an existing team: %v", err)
+	}
+	if err := teams.DeleteTeam(ctx, "frontend"); !errors.Is(err, models.ErrTeamNotEmpty) {
+		t.Fatalf("deleting a team with members: %v", err)
+	}
+
+	team, err := teams.RenameTeam(ctx, "backend", "platform")
+	if err != nil {
+		t.Fatalf("RenameTeam: %v", err)
+	}
+	if team.TeamName != "platform" || len(team.Members) != 2 {
+		t.Fatalf("renamed team = %+v", team)
+	}
+	if _, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false); err != nil {
+		t.Fatalf("CreatePR after rename: %v", err)
+	}
+
+	if _, err := svc.RemoveTeamMembers(ctx, "frontend", []string{"f1", "u1"}); err != nil {
+		t.Fatalf("RemoveTeamMembers: %v", err)
+	}
+	if err := teams.DeleteTeam(ctx, "frontend"); err != nil {
+		t.Fatalf("DeleteTeam: %v", err)
+	}
+	if _, err := teams.GetTeam(ctx, "frontend"); !errors.Is(err, models.ErrTeamNotFound) {
+		t.Fatalf("deleted team: %v", err)
+	}
+}
+
+func TestUpdateSettingsKeepsConcurrentPartialUpdates(t *testing.T) {
+	_, teams, _ := newTestTeams(t, "u1", "u2", "u3")
+	ctx := context.Background()
+
+	strategy, perPR := models.StrategyRoundRobin, 3
+	updates := []models.TeamSettingsUpdate{
+		{ReviewerStrategy: &strategy},
+		{ReviewersPerPR: &perPR},
+	}
+	var wg sync.WaitGroup
+	for _, update := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := teams.UpdateSettings(ctx, "backend", update); err != nil {
+				t.Errorf("UpdateSettings: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	settings, err := teams.GetSettings(ctx, "backend")
+	if err != nil {
+		t.Fatalf("GetSettings: %v", err)
+	}
+	if settings.ReviewerStrategy != strategy || settings.ReviewersPerPR != perPR {
+		t.Fatalf("settings = %+v, want both partial updates applied", settings)
+	}
+
+	// Validation sees the merged settings: min_reviewers may not exceed the
+	// stored reviewers_per_pr.
+	minReviewers := 4
+	if _, err := teams.UpdateSettings(ctx, "backend", models.TeamSettingsUpdate{MinReviewers: &minReviewers}); !errors.Is(err, ErrInvalidTeamSettings) {
+		t.Fatalf("min_reviewers above reviewers_per_pr: %v", err)
+	}
+	if _, err := teams.UpdateSettings(ctx, "missing", models.TeamSettingsUpdate{MinReviewers: &minReviewers}); !errors.Is(err, models.ErrTeamNotFound) {
+		t.Fatalf("UpdateSettings of a missing team: %v", err)
+	}
+}
@@ -2,43 +2,257 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"pr-reviewer-service/internal/models"
 	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const maxReviewersPerPR = 10
+
 type TeamService struct {
-	teamRepo *repository.TeamRepository
-	userRepo *repository.UserRepository
+	store    repository.Transactor
+	teamRepo repository.TeamRepo
+	userRepo repository.UserRepo
 }
 
-func NewTeamService(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository) *TeamService {
+func NewTeamService(store repository.Transactor, teamRepo repository.TeamRepo, userRepo repository.UserRepo) *TeamService {
 	return &TeamService{
+		store:    store,
 		teamRepo: teamRepo,
 		userRepo: userRepo,
 	}
 }
 
-func (s *TeamService) CreateTeam(ctx context.Context, team *models.Team) (*models.Team, error) {
-	if err := s.teamRepo.CreateTeam(ctx, team); err != nil {
+func (s *TeamService) CreateTeam(ctx context.Context, team *models.Team) (_ *models.Team, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.CreateTeam", attribute.String("team.name", team.TeamName))
+	defer tracing.End(span, &err)
+
+	if team.ReviewerStrategy != "" && !IsValidStrategy(team.ReviewerStrategy) {
+		return nil, ErrUnknownStrategy
+	}
+
+	if err := validateRoles(team.Members); err != nil {
 		return nil, err
 	}
 
-	for i := range team.Members {
-		user := &models.User{
-			UserID:   team.Members[i].UserID,
-			Username: team.Members[i].Username,
-			TeamName: team.TeamName,
-			IsActive: team.Members[i].IsActive,
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		if err := tx.Team.CreateTeam(ctx, team); err != nil {
+			return err
 		}
-		if err := s.userRepo.CreateOrUpdateUser(ctx, user); err != nil {
-			return nil, err
+
+		for _, member := range team.Members {
+			if err := joinTeam(ctx, tx, team.TeamName, member); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return team, nil
 }
 
-func (s *TeamService) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+func (s *TeamService) GetTeam(ctx context.Context, teamName string) (_ *models.Team, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.GetTeam", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
 	return s.teamRepo.GetTeam(ctx, teamName)
-}
\ No newline at end of file
+}
+
+func (s *TeamService) GetSettings(ctx context.Context, teamName string) (_ *models.TeamSettings, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.GetSettings", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	return s.teamRepo.GetSettings(ctx, teamName)
+}
+
+// UpdateSettings applies update to the current settings of teamName. The
+// team row stays locked between reading and writing, so concurrent partial
+// updates of different fields do not overwrite each other.
+func (s *TeamService) UpdateSettings(ctx context.Context, teamName string, update models.TeamSettingsUpdate) (_ *models.TeamSettings, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.UpdateSettings", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	var settings *models.TeamSettings
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		current, err := tx.Team.GetSettingsForUpdate(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		if update.ReviewerStrategy != nil {
+			current.ReviewerStrategy = *update.ReviewerStrategy
+		}
+		if update.ReviewersPerPR != nil {
+			current.ReviewersPerPR = *update.ReviewersPerPR
+		}
+		if update.MinReviewers != nil {
+			current.MinReviewers = *update.MinReviewers
+		}
+		if update.AllowFewerThanRequired != nil {
+			current.AllowFewerThanRequired = *update.AllowFewerThanRequired
+		}
+
+		if current.ReviewerStrategy != "" && !IsValidStrategy(current.ReviewerStrategy) {
+			return ErrUnknownStrategy
+		}
+
+		if current.ReviewersPerPR < 1 || current.ReviewersPerPR > maxReviewersPerPR {
+			return ErrInvalidTeamSettings
+		}
+
+		if current.MinReviewers < 0 || current.MinReviewers > current.ReviewersPerPR {
+			return ErrInvalidTeamSettings
+		}
+
+		if err := tx.Team.UpdateSettings(ctx, current); err != nil {
+			return err
+		}
+		settings = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// AddMembers adds users to an existing team that is not archived. Users may
+// belong to other teams as well; users that are already members of teamName
+// are rejected with MEMBER_EXISTS.
+func (s *TeamService) AddMembers(ctx context.Context, teamName string, members []models.TeamMember) (_ *models.Team, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.AddMembers", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	if len(members) == 0 {
+		return nil, ErrNoMembersToAdd
+	}
+
+	if err := validateRoles(members); err != nil {
+		return nil, err
+	}
+
+	var team *models.Team
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		settings, err := tx.Team.GetSettings(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		if settings.Archived {
+			return models.ErrTeamArchived
+		}
+
+		for _, member := range members {
+			_, err := tx.Membership.GetMembership(ctx, teamName, member.UserID)
+			if err == nil {
+				return models.ErrMemberExists.WithMessage(fmt.Sprintf("user %s is already a member of team %s", member.UserID, teamName))
+			}
+			if !errors.Is(err, models.ErrMembershipNotFound) {
+				return err
+			}
+
+			if err := joinTeam(ctx, tx, teamName, member); err != nil {
+				return err
+			}
+		}
+
+		team, err = tx.Team.GetTeam(ctx, teamName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// RenameTeam renames a team; its members and PRs move along with it.
+func (s *TeamService) RenameTeam(ctx context.Context, teamName string, newName string) (_ *models.Team, err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.RenameTeam", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	var team *models.Team
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		if err := tx.Team.RenameTeam(ctx, teamName, newName); err != nil {
+			return err
+		}
+
+		team, err = tx.Team.GetTeam(ctx, newName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// DeleteTeam deletes a team without members. Members have to be removed
+// first, which moves their open reviews elsewhere.
+func (s *TeamService) DeleteTeam(ctx context.Context, teamName string) (err error) {
+	ctx, span := tracing.Start(ctx, "TeamService.DeleteTeam", attribute.String("team.name", teamName))
+	defer tracing.End(span, &err)
+
+	return s.teamRepo.DeleteTeam(ctx, teamName)
+}
+
+// joinTeam makes member a member of teamName or updates their membership.
+// Unknown users are created active; for existing users only the name
+// changes, their global active flag is kept. is_active of member applies to
+// this team only. An empty role keeps the current one, or is RoleMember for
+// a new membership.
+func joinTeam(ctx context.Context, tx *repository.Repos, teamName string, member models.TeamMember) error {
+	user, err := tx.User.GetUser(ctx, member.UserID)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		// The empty name makes sure the new user is saved below.
+		user = &models.User{UserID: member.UserID, IsActive: true}
+	case err != nil:
+		return err
+	}
+
+	if user.Username != member.Username {
+		user.Username = member.Username
+		if err := tx.User.CreateOrUpdateUser(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	role := member.Role
+	if role == "" {
+		role = models.RoleMember
+		current, err := tx.Membership.GetMembership(ctx, teamName, member.UserID)
+		if err == nil {
+			role = current.Role
+		} else if !errors.Is(err, models.ErrMembershipNotFound) {
+			return err
+		}
+	}
+
+	return tx.Membership.SaveMembership(ctx, &models.TeamMembership{
+		TeamName: teamName,
+		UserID:   member.UserID,
+		Role:     role,
+		IsActive: member.IsActive,
+	})
+}
+
+func validateRoles(members []models.TeamMember) error {
+	for _, member := range members {
+		switch member.Role {
+		case "", models.RoleMember, models.RoleLead:
+		default:
+			return ErrUnknownRole
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// errDryRun rolls back the transaction of a dry-run sync after the diff and
+// the replacements have been computed.
+var errDryRun = errors.New("dry run")
+
+// SyncTeam makes teamName consist of exactly roster, creating the team if it
+// does not exist. Members missing from roster are removed and their OPEN
+// reviews of the team's PRs move to the remaining active members; users may
+// stay members of other teams. is_active and role are per team, an empty
+// role keeps the current one. Everything is applied in one transaction, so
+// syncing the same roster twice changes nothing the second time. With
+// dryRun the changes are computed and rolled back.
+func (s *PRService) SyncTeam(ctx context.Context, teamName string, roster []models.TeamMember, dryRun bool) (_ *models.TeamSyncResult, err error) {
+	ctx, span := tracing.Start(ctx, "PRService.SyncTeam",
+		attribute.String("team.name", teamName),
+		attribute.Bool("dry_run", dryRun))
+	defer tracing.End(span, &err)
+
+	if err := validateRoles(roster); err != nil {
+		return nil, err
+	}
+
+	result := &models.TeamSyncResult{
+		TeamName:     teamName,
+		DryRun:       dryRun,
+		Added:        []models.TeamMember{},
+		Removed:      []string{},
+		Renamed:      []models.MemberRename{},
+		Activated:    []string{},
+		Deactivated:  []string{},
+		RoleChanged:  []string{},
+		Replacements: []models.ReviewerReplacement{},
+	}
+
+	err = s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		settings, err := tx.Team.GetSettings(ctx, teamName)
+		if errors.Is(err, models.ErrTeamNotFound) {
+			result.Created = true
+			settings = &models.TeamSettings{TeamName: teamName}
+			err = tx.Team.CreateTeam(ctx, &models.Team{TeamName: teamName})
+		}
+		if err != nil {
+			return err
+		}
+
+		members, err := tx.User.GetTeamMembers(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		memberships, err := tx.Membership.ListTeamMemberships(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		// GetTeamMembers has the names, the memberships have the per-team
+		// active flags the roster is compared with.
+		names := make(map[string]string, len(members))
+		for _, member := range members {
+			names[member.UserID] = member.Username
+		}
+		current := make(map[string]models.TeamMembership, len(memberships))
+		for _, membership := range memberships {
+			current[membership.UserID] = membership
+		}
+
+		// Changes to the members who stay and new members are applied before
+		// removals, so that removed reviews can move to them.
+		desired := make(map[string]bool, len(roster))
+		for _, member := range roster {
+			desired[member.UserID] = true
+
+			existing, ok := current[member.UserID]
+			if !ok {
+				result.Added = append(result.Added, member)
+				if err := joinTeam(ctx, tx, teamName, member); err != nil {
+					return err
+				}
+				continue
+			}
+
+			renamed := names[member.UserID] != member.Username
+			roleChanged := member.Role != "" && member.Role != existing.Role
+			if !renamed && !roleChanged && existing.IsActive == member.IsActive {
+				continue
+			}
+			if renamed {
+				result.Renamed = append(result.Renamed, models.MemberRename{
+					UserID:      member.UserID,
+					OldUsername: names[member.UserID],
+					NewUsername: member.Username,
+				})
+			}
+			if roleChanged {
+				result.RoleChanged = append(result.RoleChanged, member.UserID)
+			}
+			if err := joinTeam(ctx, tx, teamName, member); err != nil {
+				return err
+			}
+
+			switch {
+			case member.IsActive && !existing.IsActive:
+				result.Activated = append(result.Activated, member.UserID)
+			case !member.IsActive && existing.IsActive:
+				result.Deactivated = append(result.Deactivated, member.UserID)
+				user := &models.User{UserID: member.UserID, Username: member.Username, TeamName: teamName}
+				if err := enqueue(ctx, tx.Outbox, models.EventUserDeactivated, user); err != nil {
+					return err
+				}
+			}
+		}
+
+		if settings.Archived && len(result.Added) > 0 {
+			return models.ErrTeamArchived
+		}
+
+		removed := make(map[string]bool)
+		for _, membership := range memberships {
+			if !desired[membership.UserID] {
+				removed[membership.UserID] = true
+				result.Removed = append(result.Removed, membership.UserID)
+			}
+		}
+
+		for _, userID := range result.Removed {
+			err := forEachOpenReview(ctx, tx, teamName, userID, func(pr *models.PullRequest) error {
+				replacementID, err := s.pickReplacement(ctx, tx, pr, teamName, removed)
+				if err != nil {
+					return err
+				}
+
+				replacement, err := replaceReviewer(ctx, tx, pr.ID, userID, replacementID, models.ReassignReasonRemoved)
+				if err != nil {
+					return err
+				}
+				result.Replacements = append(result.Replacements, replacement)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Membership.DeleteMembership(ctx, teamName, userID); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordReplacements(result.Replacements)
+	return result, nil
+}
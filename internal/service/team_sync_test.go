@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+)
+
+func TestSyncTeam(t *testing.T) {
+	svc, teams, m := newTestTeams(t, "u1", "u2", "u3", "u4")
+	ctx := context.Background()
+
+	pr, _, err := svc.CreatePR(ctx, "pr-1", "Add search", "u1", "", false)
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	leaving := pr.AssignedReviewers[0]
+
+	roster := []models.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}}
+	for _, id := range []string{"u2", "u3", "u4"} {
+		if id != leaving {
+			roster = append(roster, models.TeamMember{UserID: id, Username: id, IsActive: id != "u4"})
+		}
+	}
+	roster = append(roster, models.TeamMember{UserID: "u5", Username: "Eve", IsActive: true})
+
+	check := func(result *models.TeamSyncResult) {
+		t.Helper()
+		if result.Created || len(result.Added) != 1 || result.Added[0].UserID != "u5" {
+			t.Errorf("created %v, added %+v", result.Created, result.Added)
+		}
+		if !slices.Equal(result.Removed, []string{leaving}) {
+			t.Errorf("removed = %v, want [%s]", result.Removed, leaving)
+		}
+		if len(result.Renamed) != 1 || result.Renamed[0] != (models.MemberRename{UserID: "u1", OldUsername: "u1", NewUsername: "Alice"}) {
+			t.Errorf("renamed = %+v", result.Renamed)
+		}
+		wantDeactivated := []string{}
+		if leaving != "u4" {
+			wantDeactivated = []string{"u4"}
+		}
+		if !slices.Equal(result.Deactivated, wantDeactivated) || len(result.Activated) != 0 {
+			t.Errorf("activated %v, deactivated %v", result.Activated, result.Deactivated)
+		}
+		if len(result.Replacements) != 1 || result.Replacements[0].OldReviewerID != leaving || result.Replacements[0].LeftShort {
+			t.Errorf("replacements = %+v", result.Replacements)
+		}
+	}
+
+	planned, err := svc.SyncTeam(ctx, "backend", roster, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	check(planned)
+	team, err := teams.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if len(team.Members) != 4 || team.Members[0].Username != "u1" {
+		t.Fatalf("a dry run must not change the team: %+v", team.Members)
+	}
+	if m.reassigned[models.ReassignReasonRemoved] != 0 {
+		t.Errorf("a dry run must not record metrics: %v", m.reassigned)
+	}
+
+	applied, err := svc.SyncTeam(ctx, "backend", roster, false)
+	if err != nil {
+		t.Fatalf("SyncTeam: %v", err)
+	}
+	check(applied)
+	team, err = teams.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	want := make([]models.TeamMember, len(roster))
+	for i, member := range roster {
+		member.Role = models.RoleMember
+		want[i] = member
+	}
+	slices.SortFunc(want, func(a, b models.TeamMember) int { return strings.Compare(a.UserID, b.UserID) })
+	if !slices.Equal(team.Members, want) {
+		t.Fatalf("members = %+v, want %+v", team.Members, want)
+	}
+
+	again, err := svc.SyncTeam(ctx, "backend", roster, false)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if len(again.Added)+len(again.Removed)+len(again.Renamed)+len(again.Activated)+len(again.Deactivated)+len(again.Replacements) != 0 {
+		t.Fatalf("syncing the same roster again must change nothing: %+v", again)
+	}
+
+	roster[0].Role = models.RoleLead
+	promoted, err := svc.SyncTeam(ctx, "backend", roster, false)
+	if err != nil {
+		t.Fatalf("sync with a role: %v", err)
+	}
+	if !slices.Equal(promoted.RoleChanged, []string{"u1"}) || len(promoted.Renamed)+len(promoted.Activated)+len(promoted.Deactivated) != 0 {
+		t.Fatalf("role change result = %+v", promoted)
+	}
+
+	// u5 joins frontend and stays in backend.
+	created, err := svc.SyncTeam(ctx, "frontend", []models.TeamMember{{UserID: "u5", Username: "Eve", IsActive: false}}, false)
+	if err != nil {
+		t.Fatalf("sync of a new team: %v", err)
+	}
+	if !created.Created || len(created.Added) != 1 {
+		t.Fatalf("new team result = %+v", created)
+	}
+	team, err = teams.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if i := slices.IndexFunc(team.Members, func(m models.TeamMember) bool { return m.UserID == "u5" }); i < 0 || !team.Members[i].IsActive {
+		t.Fatalf("u5 in backend after joining frontend inactive: %+v", team.Members)
+	}
+
+	if _, err := svc.SyncTeam(ctx, "frontend", []models.TeamMember{{UserID: "u5", Username: "Eve", Role: "owner"}}, false); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("unknown role: %v", err)
+	}
+}
@@ -8,17 +8,44 @@ import (
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	store    repository.Transactor
+	userRepo repository.UserRepo
+	prRepo   repository.PRRepo
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(store repository.Transactor, userRepo repository.UserRepo, prRepo repository.PRRepo) *UserService {
+	return &UserService{store: store, userRepo: userRepo, prRepo: prRepo}
 }
 
 func (s *UserService) SetIsActive(ctx context.Context, userID string, isActive bool) (*models.User, error) {
-	return s.userRepo.SetUserActive(ctx, userID, isActive)
+	var user *models.User
+	err := s.store.WithTx(ctx, func(tx *repository.Repos) error {
+		var err error
+		user, err = tx.User.SetUserActive(ctx, userID, isActive)
+		if err != nil {
+			return err
+		}
+
+		if isActive {
+			return nil
+		}
+		return enqueue(ctx, tx.Outbox, models.EventUserDeactivated, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (s *UserService) GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
-	return []models.PullRequestShort{}, nil
+	prs, err := s.prRepo.GetUserReviews(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if prs == nil {
+		prs = []models.PullRequestShort{}
+	}
+	return prs, nil
 }
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"pr-reviewer-service/internal/logging"
+	"pr-reviewer-service/internal/models"
+)
+
+const dispatcherConcurrency = 8
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseBackoff: time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+// backoff returns the delay before the given retry (1-based): BaseBackoff,
+// then doubled on every attempt up to MaxBackoff.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return delay
+}
+
+type subscriptionSource interface {
+	ListActiveForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error)
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error
+	ClaimRetries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookRetry, error)
+	RescheduleRetry(ctx context.Context, retry *models.WebhookRetry) error
+	DeleteRetry(ctx context.Context, id int64) error
+}
+
+// WebhookDispatcher delivers events to every matching subscription as a
+// signed JSON POST. Each attempt is written to the delivery log. A failed
+// attempt is stored as a retry with exponential backoff and made later by
+// RetryDue, so a dead endpoint never holds up other deliveries.
+type WebhookDispatcher struct {
+	subs   subscriptionSource
+	client *http.Client
+	policy RetryPolicy
+	sem    chan struct{}
+}
+
+func NewWebhookDispatcher(subs subscriptionSource, client *http.Client, policy RetryPolicy) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		subs:   subs,
+		client: client,
+		policy: policy,
+		sem:    make(chan struct{}, dispatcherConcurrency),
+	}
+}
+
+// Dispatch makes the first delivery attempt of event to all subscriptions
+// and schedules retries for the ones that failed. An error is returned when
+// the event could not be dispatched at all, or a retry could not be stored,
+// and the event should be dispatched again later.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event *models.Event) error {
+	subs, err := d.subs.ListActiveForEvent(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions for %s: %w", event.Type, err)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return d.fanOut(ctx, len(subs), func(i int) error {
+		return d.attempt(ctx, &models.WebhookRetry{Subscription: subs[i], Event: *event, Attempt: 1}, body)
+	})
+}
+
+// RetryDue claims up to limit retries that are due, leased for lease, and
+// makes one more attempt for each of them. It returns how many were claimed.
+func (d *WebhookDispatcher) RetryDue(ctx context.Context, limit int, lease time.Duration) (int, error) {
+	retries, err := d.subs.ClaimRetries(ctx, limit, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	err = d.fanOut(ctx, len(retries), func(i int) error {
+		body, err := json.Marshal(&retries[i].Event)
+		if err != nil {
+			return err
+		}
+		return d.attempt(ctx, &retries[i], body)
+	})
+	return len(retries), err
+}
+
+// fanOut runs fn for 0..n-1 with at most dispatcherConcurrency calls at a
+// time and waits for all of them.
+func (d *WebhookDispatcher) fanOut(ctx context.Context, n int, fn func(i int) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i := range n {
+		select {
+		case d.sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-d.sem }()
+
+			if err := fn(i); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(append(errs, ctx.Err())...)
+}
+
+// attempt makes delivery attempt number retry.Attempt and then either drops
+// the retry, when the delivery succeeded or ran out of attempts, or stores
+// the next one. A retry with a zero ID has not been stored yet.
+func (d *WebhookDispatcher) attempt(ctx context.Context, retry *models.WebhookRetry, body []byte) error {
+	sub, event := &retry.Subscription, &retry.Event
+
+	statusCode, err := d.send(ctx, sub, event, body)
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Attempt:        retry.Attempt,
+		StatusCode:     statusCode,
+		Delivered:      err == nil,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	if logErr := d.subs.RecordDelivery(ctx, delivery); logErr != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to record webhook delivery",
+			slog.String("event_id", event.ID),
+			slog.Any("error", logErr))
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "webhook delivery failed",
+			slog.String("event_id", event.ID),
+			slog.String("subscription_id", sub.ID),
+			slog.Int("attempt", retry.Attempt),
+			slog.Any("error", err))
+	}
+
+	if err == nil || retry.Attempt >= d.policy.MaxAttempts {
+		if retry.ID == 0 {
+			return nil
+		}
+		return d.subs.DeleteRetry(ctx, retry.ID)
+	}
+
+	retry.NextAttemptAt = time.Now().UTC().Add(d.policy.backoff(retry.Attempt))
+	retry.Attempt++
+	if retry.ID == 0 {
+		return d.subs.ScheduleRetry(ctx, retry)
+	}
+	return d.subs.RescheduleRetry(ctx, retry)
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, sub *models.WebhookSubscription, event *models.Event, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-Delivery", event.ID)
+	req.Header.Set("X-Webhook-Signature-256", signPayload(sub.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signPayload returns "sha256=" followed by the hex HMAC-SHA256 of body,
+// the same scheme GitHub uses for X-Hub-Signature-256.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/models"
+)
+
+type fakeSubscriptionSource struct {
+	mu         sync.Mutex
+	subs       []models.WebhookSubscription
+	deliveries []models.WebhookDelivery
+	retries    []models.WebhookRetry
+	retrySeq   int64
+}
+
+func (f *fakeSubscriptionSource) ListActiveForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	return f.subs, nil
+}
+
+func (f *fakeSubscriptionSource) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = append(f.deliveries, *delivery)
+	return nil
+}
+
+func (f *fakeSubscriptionSource) ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retrySeq++
+	retry.ID = f.retrySeq
+	f.retries = append(f.retries, *retry)
+	return nil
+}
+
+// ClaimRetries returns every due retry; the tests never claim concurrently,
+// so leases are not tracked.
+func (f *fakeSubscriptionSource) ClaimRetries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookRetry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due []models.WebhookRetry
+	for _, retry := range f.retries {
+		if !retry.NextAttemptAt.After(time.Now().UTC()) && len(due) < limit {
+			due = append(due, retry)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeSubscriptionSource) RescheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.retries {
+		if f.retries[i].ID == retry.ID {
+			f.retries[i] = *retry
+		}
+	}
+	return nil
+}
+
+func (f *fakeSubscriptionSource) DeleteRetry(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retries = slices.DeleteFunc(f.retries, func(retry models.WebhookRetry) bool { return retry.ID == id })
+	return nil
+}
+
+func (f *fakeSubscriptionSource) pendingRetries() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.retries)
+}
+
+// retryUntilDone runs RetryDue until no retries are left.
+func retryUntilDone(t *testing.T, dispatcher *WebhookDispatcher, source *fakeSubscriptionSource) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for source.pendingRetries() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d retries still pending", source.pendingRetries())
+		}
+		if _, err := dispatcher.RetryDue(context.Background(), 10, time.Minute); err != nil {
+			t.Fatalf("RetryDue() error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: time.Millisecond,
+	MaxBackoff:  5 * time.Millisecond,
+}
+
+func TestWebhookDispatcherRetriesUntilDelivered(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	var gotSignature, gotEvent string
+	var gotBody []byte
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotSignature = r.Header.Get("X-Webhook-Signature-256")
+		gotEvent = r.Header.Get("X-Webhook-Event")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	source := &fakeSubscriptionSource{
+		subs: []models.WebhookSubscription{{ID: "sub-1", URL: receiver.URL, Secret: "s3cret", IsActive: true}},
+	}
+	dispatcher := NewWebhookDispatcher(source, receiver.Client(), testRetryPolicy)
+
+	event, err := newEvent(models.EventReviewerAssigned, models.ReviewerAssignment{PullRequestID: "pr-1", ReviewerID: "u2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if calls != 1 || source.pendingRetries() != 1 {
+		t.Fatalf("Dispatch made %d calls and left %d retries, want 1 and 1", calls, source.pendingRetries())
+	}
+	retryUntilDone(t, dispatcher, source)
+
+	if calls != 3 {
+		t.Errorf("receiver got %d calls, want 3", calls)
+	}
+	if gotEvent != models.EventReviewerAssigned {
+		t.Errorf("X-Webhook-Event = %q, want %q", gotEvent, models.EventReviewerAssigned)
+	}
+	if want := signPayload("s3cret", gotBody); gotSignature != want {
+		t.Errorf("X-Webhook-Signature-256 = %q, want %q", gotSignature, want)
+	}
+
+	if len(source.deliveries) != 3 {
+		t.Fatalf("recorded %d deliveries, want 3", len(source.deliveries))
+	}
+	for i, d := range source.deliveries {
+		if d.Attempt != i+1 {
+			t.Errorf("delivery %d attempt = %d, want %d", i, d.Attempt, i+1)
+		}
+	}
+	if last := source.deliveries[2]; !last.Delivered || last.StatusCode != http.StatusNoContent {
+		t.Errorf("last delivery = %+v, want delivered with 204", last)
+	}
+	if first := source.deliveries[0]; first.Delivered || first.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("first delivery = %+v, want failed with 503", first)
+	}
+}
+
+func TestWebhookDispatcherGivesUp(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dead.Close()
+	alive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer alive.Close()
+
+	source := &fakeSubscriptionSource{
+		subs: []models.WebhookSubscription{
+			{ID: "sub-1", URL: dead.URL, Secret: "a", IsActive: true},
+			{ID: "sub-2", URL: alive.URL, Secret: "b", IsActive: true},
+		},
+	}
+	dispatcher := NewWebhookDispatcher(source, http.DefaultClient, testRetryPolicy)
+
+	// The dead endpoint gets a retry instead of holding up the event.
+	event, _ := newEvent(models.EventPRMerged, models.PullRequest{ID: "pr-1"})
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if len(source.deliveries) != 2 || len(source.retries) != 1 {
+		t.Fatalf("recorded %d deliveries and %d retries, want 2 and 1", len(source.deliveries), len(source.retries))
+	}
+	if retry := source.retries[0]; retry.Subscription.ID != "sub-1" || retry.Attempt != 2 || retry.Event.ID != event.ID {
+		t.Fatalf("scheduled retry = %+v", retry)
+	}
+
+	retryUntilDone(t, dispatcher, source)
+
+	failed := 0
+	for _, d := range source.deliveries {
+		if d.SubscriptionID == "sub-1" {
+			failed++
+		}
+	}
+	if failed != testRetryPolicy.MaxAttempts {
+		t.Errorf("recorded %d deliveries to the dead endpoint, want %d", failed, testRetryPolicy.MaxAttempts)
+	}
+}
+
+func TestWebhookDispatcherDispatchFansOut(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]string{}
+	newReceiver := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = r.Header.Get("X-Webhook-Delivery")
+		}))
+	}
+
+	first, second := newReceiver("first"), newReceiver("second")
+	defer first.Close()
+	defer second.Close()
+
+	source := &fakeSubscriptionSource{
+		subs: []models.WebhookSubscription{
+			{ID: "sub-1", URL: first.URL, Secret: "a", IsActive: true},
+			{ID: "sub-2", URL: second.URL, Secret: "b", IsActive: true},
+		},
+	}
+	dispatcher := NewWebhookDispatcher(source, http.DefaultClient, testRetryPolicy)
+
+	event, _ := newEvent(models.EventPRCreated, models.PullRequest{ID: "pr-1"})
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+
+	if received["first"] != event.ID || received["second"] != event.ID {
+		t.Errorf("received = %v, want both receivers to get %s", received, event.ID)
+	}
+	if len(source.deliveries) != 2 {
+		t.Errorf("recorded %d deliveries, want 2", len(source.deliveries))
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 6, BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pr-reviewer-service/internal/models"
+)
+
+const (
+	WebhookActionOpen    = "open"
+	WebhookActionMerge   = "merge"
+	WebhookActionClose   = "close"
+	WebhookActionReopen  = "reopen"
+	WebhookActionReady   = "ready"
+	WebhookActionApprove = "approve"
+)
+
+// WebhookEvent is a provider-neutral description of a pull request event.
+// Handlers translate provider payloads into it.
+type WebhookEvent struct {
+	Provider      string
+	Action        string
+	PullRequestID string
+	Title         string
+	AuthorLogin   string
+	SenderLogin   string
+	Draft         bool
+}
+
+type WebhookResult struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type WebhookService struct {
+	prService  *PRService
+	identities *IdentityService
+}
+
+func NewWebhookService(prService *PRService, identities *IdentityService) *WebhookService {
+	return &WebhookService{
+		prService:  prService,
+		identities: identities,
+	}
+}
+
+// Apply maps the event onto the same PRService operations the REST API uses.
+// Events that cannot be applied to the local state (unknown PR, unmapped
+// author, repeated delivery) are reported as ignored rather than failed.
+func (s *WebhookService) Apply(ctx context.Context, event *WebhookEvent) (*WebhookResult, error) {
+	var err error
+	switch event.Action {
+	case WebhookActionOpen:
+		var authorID string
+		authorID, err = s.identities.ResolveUserID(ctx, event.Provider, event.AuthorLogin)
+		if err != nil {
+			if errors.Is(err, models.ErrIdentityNotFound) {
+				return ignored(fmt.Sprintf("%s login %q is not mapped to a user", event.Provider, event.AuthorLogin)), nil
+			}
+			return nil, err
+		}
+		_, _, err = s.prService.CreatePR(ctx, event.PullRequestID, event.Title, authorID, "", event.Draft)
+	case WebhookActionMerge:
+		_, err = s.prService.MergePR(ctx, event.PullRequestID, true, event.Provider+":"+event.SenderLogin)
+	case WebhookActionClose:
+		_, err = s.prService.ClosePR(ctx, event.PullRequestID)
+	case WebhookActionReopen:
+		_, _, err = s.prService.ReopenPR(ctx, event.PullRequestID)
+	case WebhookActionReady:
+		_, _, err = s.prService.MarkReady(ctx, event.PullRequestID)
+	case WebhookActionApprove:
+		var reviewerID string
+		reviewerID, err = s.identities.ResolveUserID(ctx, event.Provider, event.SenderLogin)
+		if err != nil {
+			if errors.Is(err, models.ErrIdentityNotFound) {
+				return ignored(fmt.Sprintf("%s login %q is not mapped to a user", event.Provider, event.SenderLogin)), nil
+			}
+			return nil, err
+		}
+		_, err = s.prService.SubmitReview(ctx, event.PullRequestID, reviewerID, models.ReviewApproved)
+	default:
+		return ignored("unsupported action"), nil
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, models.ErrPRExists):
+		return ignored("pull request already exists"), nil
+	case errors.Is(err, models.ErrPRNotFound):
+		return ignored("pull request is not tracked"), nil
+	case errors.Is(err, models.ErrNotAssigned):
+		return ignored("approver is not an assigned reviewer"), nil
+	case errors.Is(err, models.ErrInvalidTransition) && s.alreadyOpen(ctx, event):
+		return ignored("pull request is already in that state"), nil
+	default:
+		return nil, err
+	}
+
+	return &WebhookResult{Status: "applied"}, nil
+}
+
+// alreadyOpen reports whether a reopen or ready event was rejected because
+// the PR is already OPEN, which is what a redelivery of that event looks like.
+func (s *WebhookService) alreadyOpen(ctx context.Context, event *WebhookEvent) bool {
+	if event.Action != WebhookActionReopen && event.Action != WebhookActionReady {
+		return false
+	}
+	pr, err := s.prService.prRepo.GetPR(ctx, event.PullRequestID)
+	return err == nil && pr.Status == models.PRStatusOpen
+}
+
+func ignored(reason string) *WebhookResult {
+	return &WebhookResult{Status: "ignored", Reason: reason}
+}
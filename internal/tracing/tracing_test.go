@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pr-reviewer-service/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider that keeps finished spans in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	recorder := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/pullRequest/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "PRService.Get")
+		err := error(models.ErrPRNotFound)
+		End(span, &err)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/pullRequest/pr-1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	child, server, failed := spans[0], spans[1], spans[2]
+
+	if server.Name() != "GET /pullRequest/{id}" || spanAttr(server, "http.route").AsString() != "/pullRequest/{id}" {
+		t.Errorf("server span %q, route %v", server.Name(), spanAttr(server, "http.route"))
+	}
+	if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span does not continue the incoming trace: %v, parent %v", server.SpanContext(), server.Parent())
+	}
+	if spanAttr(server, "http.response.status_code").AsInt64() != http.StatusNotFound || server.Status().Code == codes.Error {
+		t.Errorf("server span status %v, attrs %v", server.Status(), server.Attributes())
+	}
+
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("service span is not a child of the server span")
+	}
+	if child.Status().Code == codes.Error || spanAttr(child, "error.type").AsString() != models.ErrorNotFound {
+		t.Errorf("domain errors must not fail the span: %v, attrs %v", child.Status(), child.Attributes())
+	}
+
+	if failed.Status().Code != codes.Error {
+		t.Errorf("5xx must fail the server span, got %v", failed.Status())
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := record(t)
+	tracer := QueryTracer{}
+
+	tests := []struct {
+		sql    string
+		err    error
+		name   string
+		failed bool
+	}{
+		{"\n\t\tselect user_id FROM users WHERE team_name = $1", nil, "SELECT", false},
+		{"INSERT INTO pr_reviewers VALUES ($1, $2)", errors.New("unique violation"), "INSERT", true},
+		{"SELECT 1", pgx.ErrNoRows, "SELECT", false},
+	}
+
+	for _, tt := range tests {
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: tt.sql, Args: []any{"secret"}})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: tt.err})
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != len(tests) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(tests))
+	}
+	for i, tt := range tests {
+		span := spans[i]
+		if span.Name() != tt.name || spanAttr(span, "db.query.text").AsString() != tt.sql {
+			t.Errorf("%q: span %q, attrs %v", tt.sql, span.Name(), span.Attributes())
+		}
+		if (span.Status().Code == codes.Error) != tt.failed {
+			t.Errorf("%q: status %v", tt.sql, span.Status())
+		}
+		for _, kv := range span.Attributes() {
+			if kv.Value.Emit() == "secret" {
+				t.Errorf("%q: arguments must not be recorded", tt.sql)
+			}
+		}
+	}
+}